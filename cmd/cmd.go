@@ -0,0 +1,89 @@
+// Package cmd 命令行子命令，如 desktop-app config show
+package cmd
+
+import (
+	"fmt"
+	"github.com/gookit/color"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Command 子命令的执行函数
+//    args: 子命令之后的参数
+type Command func(args []string) error
+
+// 按 命令 -> 子命令 注册的执行函数
+var commands = map[string]map[string]Command{
+	"config": {
+		"show": configShow,
+		"encrypt": configEncrypt,
+	},
+	"cookies": {
+		"import": cookiesImport,
+		"export": cookiesExport,
+	},
+}
+
+// Run 执行命令行子命令
+// param
+//    args: 命令行参数，不包含程序名
+// return
+//    bool: args是否为子命令，不是子命令时应正常启动服务
+//    error: 子命令执行失败的错误
+func Run(args []string) (bool, error){
+	if len(args)==0 {
+		return false, nil
+	}
+	subCommands, ok := commands[args[0]]
+	if !ok {
+		return false, nil
+	}
+	//加载配置等过程的提示信息输出到stderr，避免与命令的输出混在一起
+	color.SetOutput(os.Stderr)
+	if len(args)<2 {
+		return true, usage(args[0], subCommands)
+	}
+	command, ok := subCommands[args[1]]
+	if !ok {
+		return true, usage(args[0], subCommands)
+	}
+	return true, command(args[2:])
+}
+
+func usage(name string, subCommands map[string]Command) error{
+	names := make([]string, 0, len(subCommands))
+	for k := range subCommands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("用法: desktop-app %s <%s> [参数]", name, strings.Join(names, "|"))
+}
+
+// flagValue 参数中开关的值，支持 --name value 与 --name=value，返回值与去掉该开关后的其他参数
+func flagValue(args []string, name string) (string, []string){
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i<len(args); i++ {
+		switch {
+		case args[i]=="--" + name && i+1<len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--" + name + "="):
+			value = strings.TrimPrefix(args[i], "--" + name + "=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return value, rest
+}
+
+// hasFlag 参数中是否包含指定的开关，如 --json
+func hasFlag(args []string, name string) bool{
+	for _, arg := range args {
+		if arg=="--" + name {
+			return true
+		}
+	}
+	return false
+}
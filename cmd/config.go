@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/abeir/desktop-app/core/config"
+	"io"
+	"os"
+	"strings"
+)
+
+// configShow 输出合并各层配置后实际生效的配置，敏感信息已遮盖
+// 参数与启动服务时相同，如 --profile prod、--server.port 9000，另外支持 --json 以json格式输出
+func configShow(args []string) error{
+	app := config.NewApplicationConfig().SetArgs(args)
+	appErr := app.Load()
+	if _, ok := appErr.(*config.ValidationError); appErr!=nil && !ok {
+		return appErr
+	}
+	api := config.NewApiConfig().SetProfile(app.Environment)
+	apiErr := api.Load()
+	if _, ok := apiErr.(*config.ValidationError); apiErr!=nil && !ok {
+		return apiErr
+	}
+
+	effective := config.NewEffectiveConfig(app, api)
+	if hasFlag(args, "json") {
+		data, err := json.MarshalIndent(effective, "", "  ")
+		if err!=nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}else{
+		effective.Print(os.Stdout)
+	}
+	for _, w := range app.Warnings() {
+		_, _ = fmt.Fprintf(os.Stderr, "警告: %s\n", w)
+	}
+	//校验不通过时仍然输出配置，便于排查问题
+	if appErr!=nil {
+		return appErr
+	}
+	return apiErr
+}
+
+// configEncrypt 加密配置值，输出的 enc: 开头的密文可直接写入application.yml
+// 未提供参数时从标准输入读取一行，避免明文留在shell历史中
+func configEncrypt(args []string) error{
+	var plain string
+	if len(args)>0 {
+		plain = args[0]
+	}else{
+		_, _ = fmt.Fprint(os.Stderr, "请输入要加密的值: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err!=nil && err!=io.EOF {
+			return err
+		}
+		plain = strings.TrimRight(line, "\r\n")
+	}
+	if plain=="" {
+		return errors.New("用法: desktop-app config encrypt <value>")
+	}
+	encrypted, err := config.Encrypt(plain)
+	if err!=nil {
+		return err
+	}
+	fmt.Println(encrypted)
+	_, _ = fmt.Fprintln(os.Stderr, "密钥文件:", config.SecretKeyFile())
+	return nil
+}
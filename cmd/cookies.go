@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/net"
+	"io"
+	"os"
+)
+
+// cookiesImport 从Netscape cookies.txt格式的文件中导入cookie，可用于从浏览器中导入登录状态
+// 用法: desktop-app cookies import [--account 账号] [文件]，未指定文件时从标准输入读取
+func cookiesImport(args []string) error{
+	account, rest := flagValue(args, "account")
+	jar, err := net.AccountJar(account)
+	if err!=nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if len(rest)>0 {
+		f, err := os.Open(rest[0])
+		if err!=nil {
+			return err
+		}
+		defer core.CloseQuietly(f)
+		in = f
+	}
+	n, err := jar.ImportNetscape(in)
+	if err!=nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "已导入%d个cookie: %s\n", n, jar.File())
+	return nil
+}
+
+// cookiesExport 以Netscape cookies.txt格式导出账号的cookie，导出的内容包含登录状态，注意妥善保管
+// 用法: desktop-app cookies export [--account 账号] [文件]，未指定文件时输出到标准输出
+func cookiesExport(args []string) error{
+	account, rest := flagValue(args, "account")
+	jar, err := net.AccountJar(account)
+	if err!=nil {
+		return err
+	}
+	if len(rest)==0 {
+		return jar.ExportNetscape(os.Stdout)
+	}
+	f, err := os.OpenFile(rest[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err!=nil {
+		return err
+	}
+	if err = jar.ExportNetscape(f); err!=nil {
+		core.CloseQuietly(f)
+		return err
+	}
+	return f.Close()
+}
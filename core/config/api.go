@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/abeir/desktop-app/core"
-	"gopkg.in/yaml.v2"
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/gookit/color"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 // NewApiConfig 创建ApiConfig
@@ -15,60 +20,264 @@ func NewApiConfig() *ApiConfig{
 	return &ApiConfig{load:Unload}
 }
 
+// api响应内容的类型
+const (
+	ResponseText = "text"
+	ResponseJson = "json"
+	ResponseBytes = "bytes"
+)
+
+// Api 一个接口的请求定义，请求头、查询参数与请求体的值中可以使用 {name} 形式的参数，
+// 参数来自url节点与调用时提供的参数
+//
+// 示例：
+//	- id: 'query_ticket'
+//	  url: '{u12306}/otn/leftTicket/query'
+//	  method: 'GET'
+//	  headers:
+//	    Referer: '{u12306}/otn/leftTicket/init'
+//	  query:
+//	    leftTicketDTO.train_date: '{date}'
+//	  timeout: '10s'
+//	  retries: 2
+//	  response: 'json'
 type Api struct {
 	Id string `json:"id" yaml:"id"`
 	Name string `json:"name" yaml:"name"`
 	Url string `json:"url" yaml:"url"`
-	Get string `json:"get" yaml:"get"`
+	// 查询字符串，如 station_version=1.9137，与Query合并
+	Get string `json:"get,omitempty" yaml:"get,omitempty"`
+	// 请求方法，默认为GET
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// 请求头
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// 查询参数
+	Query map[string]string `json:"query,omitempty" yaml:"query,omitempty"`
+	// 请求体模板
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+	// 请求体的Content-Type，默认为application/x-www-form-urlencoded
+	ContentType string `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	// 超时时间，如 10s，未配置时使用http客户端的默认超时时间
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// 请求失败、服务端错误或网络繁忙时的重试次数，POST等非幂等的请求不重试
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// 响应内容的类型：text、json、bytes，默认为text
+	Response string `json:"response,omitempty" yaml:"response,omitempty"`
 }
 
 func (a *Api) IsEmpty() bool {
 	return a.Id==""
 }
 
+// HttpMethod 大写的请求方法，未配置时为GET
+func (a *Api) HttpMethod() string{
+	if a.Method=="" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(a.Method)
+}
+
+// TimeoutDuration 超时时间，未配置或格式错误时返回0
+func (a *Api) TimeoutDuration() time.Duration{
+	if a.Timeout=="" {
+		return 0
+	}
+	d, _ := time.ParseDuration(a.Timeout)
+	return d
+}
+
+// ResponseType 响应内容的类型，未配置时为text
+func (a *Api) ResponseType() string{
+	if a.Response=="" {
+		return ResponseText
+	}
+	return strings.ToLower(a.Response)
+}
+
 type ApiConfig struct {
 	Urls map[string]string  `json:"url" yaml:"url"`
 	Apis []Api 	`json:"api" yaml:"api"`
+	// 各环境的url与api，加载时使用当前环境的配置覆盖url与api节点
+	Profiles map[string]ApiProfile 	`json:"profiles,omitempty" yaml:"profiles,omitempty"`
 	//加载配置状态
 	load LoadState
+	//当前环境，与应用配置的environment一致
+	profile string
+	//已加载的配置文件路径
+	file string
+	//api.d目录，其中的配置文件按文件名顺序合并到主配置文件之后
+	dir string
+	//已加载的所有配置文件，按合并顺序排列
+	files []string
+	//Apis中每个api所在的配置文件，与Apis一一对应
+	origins []apiOrigin
+}
+
+// apiOrigin api所在的配置文件以及在该文件中的下标
+type apiOrigin struct {
+	file string
+	index int
+	//所在的节点，如 profiles.dev.，为空时位于api节点
+	prefix string
+}
+
+// field api在配置文件中的位置，如 api[0]、profiles.dev.api[1]
+func (o apiOrigin) field() string{
+	return fmt.Sprintf("%sapi[%d]", o.prefix, o.index)
 }
 
-func (a *ApiConfig) parseUrl() {
-	if a.Apis==nil || len(a.Apis)==0{
-		return
+// parseUrl 解析url节点中url之间的引用，再将api的url中引用的url替换为实际的值
+// 其他参数、默认值与函数在执行api时解析
+func (a *ApiConfig) parseUrl() error{
+	tmpl := core.NewTemplate()
+	urls, err := tmpl.ResolveArgs(a.Urls)
+	if err!=nil {
+		return fmt.Errorf("解析url节点失败：%w", err)
 	}
+	a.Urls = urls
 	for i, api := range a.Apis {
-		a.Apis[i].Url = core.NewTemplate().Parse(api.Url, a.Urls)
+		a.Apis[i].Url = tmpl.Parse(api.Url, a.Urls)
 	}
+	return nil
 }
 
-func (a *ApiConfig) findYml() (string, error){
+// findFile 查找api配置文件，先尝试环境变量中的文件，再依次从 paths.ConfigDirs 中的目录查找 api.yml、api.json 等
+// 未找到时返回空字符串，此时仍可只使用api.d目录中的配置
+func (a *ApiConfig) findFile() (string, error){
 	configPath := os.Getenv(ApiEnvVar)
 	if configPath!="" {
 		if core.IsExists(configPath) {
+			if _, ok := DecoderFor(configPath); !ok {
+				return "", unsupportedFileError(configPath)
+			}
 			return configPath, nil
 		}
 	}
-	currentPath, err := core.CurrentPath()
+	return paths.FindConfigFile(configFileNames("api")...), nil
+}
+
+// findDir 查找api.d目录，优先使用主配置文件所在目录下的api.d，没有主配置文件时依次从 paths.ConfigDirs 中查找
+func (a *ApiConfig) findDir(file string) string{
+	if file!="" {
+		dir := filepath.Join(filepath.Dir(file), ApiDir)
+		if core.IsExists(dir) {
+			return dir
+		}
+		return ""
+	}
+	for _, configDir := range paths.ConfigDirs() {
+		dir := filepath.Join(configDir, ApiDir)
+		if core.IsExists(dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// dirFiles api.d目录中可解析的配置文件，按文件名排序
+func dirFiles(dir string) ([]string, error){
+	if dir=="" {
+		return nil, nil
+	}
+	infos, err := ioutil.ReadDir(dir)
 	if err!=nil {
-		return "", fmt.Errorf("获取当前路径失败：%w", err)
+		return nil, fmt.Errorf("读取%s目录失败：%w", ApiDir, err)
 	}
-	return filepath.Join(currentPath, "config", "api.yml"), nil
+	var files []string
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if _, ok := DecoderFor(info.Name()); ok {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
 }
 
-func (a *ApiConfig) loadFromYml(path string) error{
-	fmt.Println("加载api.yml：", path)
-	data, err := ioutil.ReadFile(path)
+// loadFiles 依次加载主配置文件与api.d目录中的配置文件，url后加载的覆盖先加载的，api的id在不同文件中重复时返回错误
+func (a *ApiConfig) loadFiles(file, dir string) error{
+	files, err := dirFiles(dir)
 	if err!=nil {
-		return fmt.Errorf("读取api.yml失败：%w", err)
+		return err
+	}
+	if file!="" {
+		files = append([]string{file}, files...)
+	}
+	if len(files)==0 {
+		return errors.New("api配置文件不存在: api.* 或 " + ApiDir + " in " + strings.Join(paths.ConfigDirs(), ", "))
+	}
+	a.Urls = make(map[string]string)
+	a.Apis = nil
+	a.Profiles = make(map[string]ApiProfile)
+	a.origins = nil
+	v := &ValidationError{}
+	for _, f := range files {
+		part, err := a.loadFromFile(f)
+		if err!=nil {
+			return err
+		}
+		a.merge(part, f, v)
 	}
-	err = yaml.Unmarshal(data, a)
+	profileFile, err := a.applyProfile(file, v)
 	if err!=nil {
-		return fmt.Errorf("解析api.yml失败：%w", err)
+		return err
+	}
+	if profileFile!="" {
+		files = append(files, profileFile)
+	}
+	if err = v.orNil(); err!=nil {
+		return err
 	}
+	a.file = file
+	a.dir = dir
+	a.files = files
 	return nil
 }
 
+func (a *ApiConfig) loadFromFile(path string) (*ApiConfig, error){
+	color.Println("<light_green>load api from:</>", path)
+	data, err := ioutil.ReadFile(path)
+	if err!=nil {
+		return nil, fmt.Errorf("读取api配置失败：%w", err)
+	}
+	part := &ApiConfig{}
+	err = decodeFile(path, data, part)
+	if err!=nil {
+		return nil, fmt.Errorf("解析api配置失败：%s, %w", path, err)
+	}
+	return part, nil
+}
+
+// merge 合并一个配置文件中的内容，不同文件中重复的api的id记录在v中
+func (a *ApiConfig) merge(part *ApiConfig, file string, v *ValidationError){
+	for k, url := range part.Urls {
+		a.Urls[k] = url
+	}
+	for i, api := range part.Apis {
+		if j := a.indexOf(api.Id); api.Id!="" && j>=0 && a.origins[j].file!=file {
+			v.add(file, fmt.Sprintf("api[%d].id", i), "id重复: %s，已在 %s 的 %s 中定义",
+				api.Id, a.origins[j].file, a.origins[j].field())
+			continue
+		}
+		a.Apis = append(a.Apis, api)
+		a.origins = append(a.origins, apiOrigin{file: file, index: i})
+	}
+	for name, profile := range part.Profiles {
+		a.Profiles[name] = a.Profiles[name].merge(profile, file, name)
+	}
+}
+
+func (a *ApiConfig) indexOf(id string) int{
+	for i, api := range a.Apis {
+		if api.Id==id {
+			return i
+		}
+	}
+	return -1
+}
+
 func (a *ApiConfig) Load() error{
 	var err  error
 	if a.load == Loading {
@@ -85,15 +294,73 @@ func (a *ApiConfig) Load() error{
 			a.load = Unload
 		}
 	}()
-	file, err := a.findYml()
+	file, err := a.findFile()
 	if err!=nil {
 		return err
 	}
-	if err=a.loadFromYml(file); err!=nil {
+	if err=a.loadFiles(file, a.findDir(file)); err!=nil {
 		return err
 	}
-	a.parseUrl()
-	return nil
+	if err=a.parseUrl(); err!=nil {
+		return err
+	}
+	err = a.Validate()
+	return err
+}
+
+// Reload 从已加载的api配置文件与api.d目录重新解析出一个新的api配置，当前配置不会被修改
+// 解析失败时返回错误，调用方应继续使用当前配置
+func (a *ApiConfig) Reload() (*ApiConfig, error){
+	return a.reloadAs(a.profile)
+}
+
+// reloadAs 使用指定的环境重新解析api配置
+func (a *ApiConfig) reloadAs(profile string) (*ApiConfig, error){
+	if a.load != Loaded {
+		return nil, errors.New("api配置尚未加载，无法重新加载")
+	}
+	fresh := NewApiConfig().SetProfile(profile)
+	fresh.load = Loading
+	dir := a.dir
+	if dir=="" {
+		dir = a.findDir(a.file)
+	}
+	if err := fresh.loadFiles(a.file, dir); err!=nil {
+		return nil, err
+	}
+	if err := fresh.parseUrl(); err!=nil {
+		return nil, err
+	}
+	if err := fresh.Validate(); err!=nil {
+		return nil, err
+	}
+	fresh.load = Loaded
+	return fresh, nil
+}
+
+// File 已加载的api主配置文件路径，只使用api.d目录时为空字符串
+func (a *ApiConfig) File() string{
+	return a.file
+}
+
+// Dir 已加载的api.d目录，不存在时为空字符串
+func (a *ApiConfig) Dir() string{
+	return a.dir
+}
+
+// Files 已加载的所有api配置文件，按合并顺序排列
+func (a *ApiConfig) Files() []string{
+	files := make([]string, len(a.files))
+	copy(files, a.files)
+	return files
+}
+
+// FileOf 获取api所在的配置文件
+func (a *ApiConfig) FileOf(id string) string{
+	if i := a.indexOf(id); i>=0 && i<len(a.origins) {
+		return a.origins[i].file
+	}
+	return ""
 }
 
 func (a *ApiConfig) IsEmpty() bool{
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/paths"
+	"path/filepath"
+)
+
+// ApiProfile 一个环境中覆盖的url与api，可以写在api配置文件的profiles节点中：
+//	profiles:
+//	  dev:
+//	    url:
+//	      u12306: 'http://127.0.0.1:9000'
+//	    api:
+//	      - id: 'station_name'
+//	        timeout: '1s'
+// 也可以写在主配置文件所在目录的 api-<环境>.yml 中，格式与api.yml相同，其中的profiles节点不生效
+// 覆盖的顺序为：api.yml、api.d目录、profiles节点、api-<环境>.yml
+type ApiProfile struct {
+	Urls map[string]string 	`json:"url,omitempty" yaml:"url,omitempty"`
+	// 按id覆盖api中已配置的字段，id不存在时作为该环境独有的api
+	Apis []Api 	`json:"api,omitempty" yaml:"api,omitempty"`
+	//Apis中每个api所在的配置文件，与Apis一一对应
+	origins []apiOrigin
+}
+
+// merge 合并不同配置文件中同一环境的配置，后加载的url覆盖先加载的
+func (p ApiProfile) merge(other ApiProfile, file string, name string) ApiProfile{
+	merged := ApiProfile{
+		Urls: make(map[string]string, len(p.Urls) + len(other.Urls)),
+		Apis: append([]Api{}, p.Apis...),
+		origins: append([]apiOrigin{}, p.origins...),
+	}
+	for k, v := range p.Urls {
+		merged.Urls[k] = v
+	}
+	for k, v := range other.Urls {
+		merged.Urls[k] = v
+	}
+	for i, api := range other.Apis {
+		merged.Apis = append(merged.Apis, api)
+		merged.origins = append(merged.origins, apiOrigin{file: file, index: i, prefix: "profiles." + name + "."})
+	}
+	return merged
+}
+
+// SetProfile 设置当前环境，需要在Load之前调用，通常为 ApplicationConfig.Environment
+func (a *ApiConfig) SetProfile(profile string) *ApiConfig{
+	a.profile = profile
+	return a
+}
+
+// Profile 当前环境
+func (a *ApiConfig) Profile() string{
+	return a.profile
+}
+
+// profileFileBase 当前环境的api覆盖文件名，不含扩展名
+func (a *ApiConfig) profileFileBase() string{
+	return "api-" + a.profile
+}
+
+// findProfileFile 查找当前环境的api覆盖文件，有主配置文件时只在其所在目录查找
+func (a *ApiConfig) findProfileFile(file string) string{
+	if a.profile=="" {
+		return ""
+	}
+	names := configFileNames(a.profileFileBase())
+	if file=="" {
+		return paths.FindConfigFile(names...)
+	}
+	for _, name := range names {
+		profileFile := filepath.Join(filepath.Dir(file), name)
+		if core.IsExists(profileFile) {
+			return profileFile
+		}
+	}
+	return ""
+}
+
+// isProfileFile 文件是否可以作为当前环境的api覆盖文件
+func (a *ApiConfig) isProfileFile(file string) bool{
+	if a.profile=="" || a.file=="" || filepath.Dir(filepath.Clean(a.file))!=filepath.Dir(file) {
+		return false
+	}
+	base := filepath.Base(file)
+	if _, ok := DecoderFor(base); !ok {
+		return false
+	}
+	return base[:len(base)-len(filepath.Ext(base))]==a.profileFileBase()
+}
+
+// applyProfile 使用当前环境的profiles节点与api-<环境>.yml覆盖url与api
+// return
+//    string: 加载的覆盖文件，不存在时为空字符串
+//    error: 读取或解析覆盖文件失败
+func (a *ApiConfig) applyProfile(file string, v *ValidationError) (string, error){
+	if a.profile=="" {
+		return "", nil
+	}
+	if profile, ok := a.Profiles[a.profile]; ok {
+		a.override(profile, v)
+	}
+	profileFile := a.findProfileFile(file)
+	if profileFile=="" {
+		return "", nil
+	}
+	part, err := a.loadFromFile(profileFile)
+	if err!=nil {
+		return "", err
+	}
+	overrides := ApiProfile{Urls: part.Urls, Apis: part.Apis}
+	for i := range part.Apis {
+		overrides.origins = append(overrides.origins, apiOrigin{file: profileFile, index: i})
+	}
+	a.override(overrides, v)
+	return profileFile, nil
+}
+
+// override 使用环境中的配置覆盖url与api
+func (a *ApiConfig) override(profile ApiProfile, v *ValidationError){
+	for k, url := range profile.Urls {
+		a.Urls[k] = url
+	}
+	for i, api := range profile.Apis {
+		origin := profile.origins[i]
+		if api.Id=="" {
+			v.add(origin.file, origin.field() + ".id", "id不能为空")
+			continue
+		}
+		if j := a.indexOf(api.Id); j>=0 {
+			overrideApi(&a.Apis[j], api)
+			continue
+		}
+		a.Apis = append(a.Apis, api)
+		a.origins = append(a.origins, origin)
+	}
+}
+
+// overrideApi 使用o中已配置的字段覆盖base，请求头与查询参数按名称合并
+func overrideApi(base *Api, o Api){
+	if o.Name!="" {
+		base.Name = o.Name
+	}
+	if o.Url!="" {
+		base.Url = o.Url
+	}
+	if o.Get!="" {
+		base.Get = o.Get
+	}
+	if o.Method!="" {
+		base.Method = o.Method
+	}
+	base.Headers = mergeStringMap(base.Headers, o.Headers)
+	base.Query = mergeStringMap(base.Query, o.Query)
+	if o.Body!="" {
+		base.Body = o.Body
+	}
+	if o.ContentType!="" {
+		base.ContentType = o.ContentType
+	}
+	if o.Timeout!="" {
+		base.Timeout = o.Timeout
+	}
+	if o.Retries!=0 {
+		base.Retries = o.Retries
+	}
+	if o.Response!="" {
+		base.Response = o.Response
+	}
+}
+
+func mergeStringMap(base, other map[string]string) map[string]string{
+	if len(other)==0 {
+		return base
+	}
+	merged := make(map[string]string, len(base) + len(other))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
@@ -1,18 +1,25 @@
 package config
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/abeir/desktop-app/core/paths"
 	"github.com/gookit/color"
-	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"strings"
 )
 
 func NewApplicationConfig() *ApplicationConfig{
-	return &ApplicationConfig{load:Unload}
+	return &ApplicationConfig{
+		load: Unload,
+		args: os.Args[1:],
+		sources: make(map[string]Source),
+		encrypted: make(map[string]bool),
+		migrations: ApplicationMigrations,
+	}
 }
 
 type ApplicationConfig struct {
@@ -20,57 +27,65 @@ type ApplicationConfig struct {
 	EnvironmentConfig
 	//配置加载状态
 	load LoadState
+	//已加载的配置文件路径
+	file string
+	//用于覆盖配置的命令行参数
+	args []string
+	//每个配置值的来源
+	sources map[string]Source
+	//配置文件中是否存在environment对应的环境配置
+	profileFound bool
+	//解密过的配置项
+	encrypted map[string]bool
+	//升级配置文件使用的升级函数
+	migrations *migrate.Registry
+	//加载时执行的升级函数
+	migrated []migrate.Migration
+	//升级后是否写回配置文件
+	rewriteMigrated bool
+	//校验时发现的警告
+	warnings []*FieldError
 }
 
 
-func (c *ApplicationConfig) findEnvironmentConfig(content *ConfigContent, env string) {
-	for _, config := range content.Configurations {
-		if config.Profile==env {
-			c.EnvironmentConfig = config
-			color.Printf("<light_green>global config:</> %+v\n", c.EnvironmentConfig)
-			return
-		}
+//选择环境并合并各层配置，环境配置会先按继承关系与 defaults 合并，合并后解密 enc: 开头的配置值
+func (c *ApplicationConfig) resolve(content *ConfigContent, path string) error{
+	c.file = path
+	c.Environment = content.Environment
+	c.sources["environment"] = Source{Layer: LayerFile, Location: path}
+	if profile, source := c.profileOverride(); profile!="" {
+		c.Environment = profile
+		c.sources["environment"] = source
 	}
-}
-
-func (c *ApplicationConfig) loadFromJson(path string) error{
-	color.Println("<light_green>load config from:</>", path)
-	data, err := ioutil.ReadFile(path)
+	fileConfig, origins, found, err := inheritEnvironmentConfig(content, path, c.Environment)
 	if err!=nil {
 		return err
 	}
-	content := &ConfigContent{}
-	err = json.Unmarshal(data, content)
-	if err!=nil {
+	c.profileFound = found
+	c.applyLayers(fileConfig, origins, path)
+	if err = c.decryptSecrets(); err!=nil {
 		return err
 	}
-	c.Environment = content.Environment
-	c.findEnvironmentConfig(content, content.Environment)
+	color.Println("<light_green>active profile:</>", c.Environment)
 	return nil
 }
 
-func (c *ApplicationConfig) loadFromYml(path string) error{
+//根据扩展名选择解析器加载配置文件，扩展名未注册时返回错误
+func (c *ApplicationConfig) loadFile(path string) error{
 	color.Println("<light_green>load config from:</>", path)
 	data, err := ioutil.ReadFile(path)
 	if err!=nil {
 		return err
 	}
-	content := &ConfigContent{}
-	err = yaml.Unmarshal(data, content)
+	data, decoder, err := c.migrateFile(path, data)
 	if err!=nil {
 		return err
 	}
-	c.Environment = content.Environment
-	c.findEnvironmentConfig(content, content.Environment)
-	return nil
-}
-
-func (c *ApplicationConfig) currentPath() (string, error) {
-	dir, err := os.Executable()
-	if err != nil {
-		return "", err
+	content := &ConfigContent{}
+	if err = decoder(data, content); err!=nil {
+		return err
 	}
-	return filepath.Dir(dir), nil
+	return c.resolve(content, path)
 }
 
 //从环境变量中获取配置文件路径
@@ -90,16 +105,11 @@ func (c *ApplicationConfig) loadFromEnvVar() (bool, error){
 		return false, nil
 	}
 	color.Println("<light_green>load config from environment variable:</>", ApplicationEnvVar, configFile)
-	extName := filepath.Ext(configFile)
-	if extName==".yaml" || extName==".yml" {
-		return true, c.loadFromYml(configFile)
-	}else if extName==".json" {
-		return true, c.loadFromJson(configFile)
-	}
-	return false, nil
+	return true, c.loadFile(configFile)
 }
 
-//Load 加载配置文件，先尝试从环境变量中的配置文件位置中读取，再尝试从程序所在位置下的config目录中读取
+//Load 加载配置文件，先尝试从环境变量中的配置文件位置中读取，再依次尝试从 paths.ConfigDirs 中的目录读取
+//加载后会校验配置，校验不通过时返回 *ValidationError，其中包含所有的错误
 func (c *ApplicationConfig) Load() error{
 	var err error
 	if c.load == Loading {
@@ -119,44 +129,79 @@ func (c *ApplicationConfig) Load() error{
 		}
 	}()
 
+	if err = c.loadConfig(); err!=nil {
+		return err
+	}
+	err = c.Validate()
+	return err
+}
+
+//先尝试从环境变量中的配置文件位置中读取，再依次尝试从 paths.ConfigDirs 中的目录读取
+func (c *ApplicationConfig) loadConfig() error{
 	//首先，尝试从环境变量中读取配置文件
-	var isLoad bool
-	isLoad, err = c.loadFromEnvVar()
+	isLoad, err := c.loadFromEnvVar()
 	if err!=nil {
 		return err
 	}
 	if isLoad {
 		return nil
 	}
-	//从当前目录下读取
-	var currentPath string
-	currentPath, err = c.currentPath()
-	if err!=nil {
+	//按 paths.ConfigDirs 的顺序查找，同一目录中按解析器的注册顺序查找 application.yml、application.yaml、application.json 等
+	if configFile := paths.FindConfigFile(configFileNames("application")...); configFile!="" {
+		return c.loadFile(configFile)
+	}
+	return fmt.Errorf("%w: application.* in %s", ErrConfigNotFound, strings.Join(paths.ConfigDirs(), ", "))
+}
+
+// LoadDefaults 没有配置文件时，仅使用内置默认值、环境变量与命令行参数加载配置，用于首次运行时的设置向导
+func (c *ApplicationConfig) LoadDefaults() error{
+	if c.load != Unload {
+		return errors.New("应用配置已加载，请不要重复调用Load")
+	}
+	profile, source := c.profileOverride()
+	if profile=="" {
+		profile, source = DefaultProfile, Source{Layer: LayerDefault}
+	}
+	c.Environment = profile
+	c.profileFound = true
+	c.applyLayers(EnvironmentConfig{Profile: profile}, nil, "")
+	c.sources["environment"] = source
+	if err := c.decryptSecrets(); err!=nil {
 		return err
 	}
-	configFile := filepath.Join(currentPath, "config", "application.yml")
-	if core.IsExists(configFile) {
-		if err = c.loadFromYml(configFile); err!=nil {
-			return err
-		}
-		return nil
+	if err := c.Validate(); err!=nil {
+		return err
 	}
-	configFile = filepath.Join(currentPath, "config", "application.yaml")
-	if core.IsExists(configFile) {
-		if err = c.loadFromYml(configFile); err!=nil {
-			return err
-		}
-		return nil
+	c.load = Loaded
+	return nil
+}
+
+// Reload 从已加载的配置文件重新解析出一个新的配置，当前配置不会被修改
+// 配置文件解析失败或校验不通过时返回错误，调用方应继续使用当前配置
+// return
+//    *ApplicationConfig: 重新解析得到的配置
+//    error: 重新加载失败的错误
+func (c *ApplicationConfig) Reload() (*ApplicationConfig, error){
+	if c.load != Loaded {
+		return nil, errors.New("应用配置尚未加载，无法重新加载")
 	}
-	configFile = filepath.Join(currentPath, "config", "application.json")
-	if core.IsExists(configFile) {
-		if err = c.loadFromJson(configFile); err!=nil {
-			return err
-		}
-		return nil
+	fresh := NewApplicationConfig()
+	fresh.args = c.args
+	fresh.migrations = c.migrations
+	fresh.load = Loading
+	if err := fresh.loadFile(c.file); err!=nil {
+		return nil, err
 	}
-	err = errors.New("configuration file not found: " + configFile)
-	return err
+	if err := fresh.Validate(); err!=nil {
+		return nil, err
+	}
+	fresh.load = Loaded
+	return fresh, nil
+}
+
+// File 已加载的配置文件路径
+func (c *ApplicationConfig) File() string{
+	return c.file
 }
 
 func (c *ApplicationConfig) IsDev() bool{
@@ -177,33 +222,194 @@ func (c *ApplicationConfig) Is(env string) bool{
 
 
 type Database struct {
-	Name string 	`json:"name" yaml:"name"`
-	Url string 		`json:"url" yaml:"url"`
+	Name string 	`json:"name" yaml:"name,omitempty"`
+	Url string 		`json:"url" yaml:"url,omitempty"`
 }
 
 type Server struct {
-	Port string 	`json:"port" yaml:"port"`
+	Port string 	`json:"port" yaml:"port,omitempty"`
+	// 请求日志
+	AccessLog AccessLog 	`json:"accessLog" yaml:"accessLog,omitempty"`
+}
+
+// 请求日志的格式
+const (
+	AccessLogFields = "fields"
+	AccessLogCombined = "combined"
+)
+
+// AccessLog 请求日志
+//
+// 示例：
+//	accessLog:
+//	  format: '{method} {uri} {status} {latencyMs}ms'
+//	  skip: '/assets/*,/favicon.ico'
+//	  slowThreshold: 1s
+//	  bodySize: 4KB
+type AccessLog struct {
+	// 格式：fields 以字段记录，combined 为Apache combined格式，其他为自定义模板，
+	// 模板中可以使用 {method} {uri} {path} {status} {latencyMs} {clientIp} {requestId} {size} {userAgent} {referer} {protocol} {time}
+	Format string 			`json:"format" yaml:"format,omitempty"`
+	// 不记录的路径，以 , 分隔，支持path.Match的通配符，以 /* 结尾时包括所有子路径，如 /assets/*
+	Skip string 			`json:"skip" yaml:"skip,omitempty"`
+	// 处理时间超过此值的请求以Warn级别记录，如 1s
+	SlowThreshold string 	`json:"slowThreshold" yaml:"slowThreshold,omitempty"`
+	// 在Debug级别记录请求与响应内容的最大长度，如 4KB，未配置时不记录
+	BodySize string 		`json:"bodySize" yaml:"bodySize,omitempty"`
+}
+
+// SkipPatterns 不记录的路径
+func (a *AccessLog) SkipPatterns() []string{
+	var patterns []string
+	for _, pattern := range strings.Split(a.Skip, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern!="" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
 
 type Logger struct {
-	Level string 		`json:"level" yaml:"level"`
-	Path string 		`json:"path" yaml:"path"`
-	Filename string 	`json:"filename" yaml:"filename"`
-	MaxAge string 		`json:"maxAge" yaml:"maxAge"`
-	RotationTime string 	`json:"rotationTime" yaml:"rotationTime"`
+	Level string 		`json:"level" yaml:"level,omitempty"`
+	Path string 		`json:"path" yaml:"path,omitempty"`
+	Filename string 	`json:"filename" yaml:"filename,omitempty"`
+	MaxAge string 		`json:"maxAge" yaml:"maxAge,omitempty"`
+	RotationTime string 	`json:"rotationTime" yaml:"rotationTime,omitempty"`
+	// 各模块的日志级别，以 , 分隔，如 net=debug,service.station=trace，未配置的模块使用上级模块或level的级别
+	Modules string 		`json:"modules" yaml:"modules,omitempty"`
+	// 日志输出，未配置时输出到控制台与path中的filename文件
+	Sinks []LogSink 	`json:"sinks" yaml:"sinks,omitempty"`
+	// 写入日志前需要遮盖的敏感信息，在内置的字段与正则表达式之外添加
+	Redact Redact 		`json:"redact" yaml:"redact,omitempty"`
+}
+
+// Redact 日志中需要遮盖的敏感信息
+//
+// 示例：
+//	redact:
+//	  fields: [ 'passengerName' ]
+//	  patterns: [ 'bankCard=(\d+)' ]
+type Redact struct {
+	// 字段名，不区分大小写，字段的值整体遮盖
+	Fields []string 	`json:"fields,omitempty" yaml:"fields,omitempty"`
+	// 正则表达式，存在分组时只遮盖第一个分组，否则遮盖整个匹配的内容
+	Patterns []string 	`json:"patterns,omitempty" yaml:"patterns,omitempty"`
+}
+
+// 日志输出的类型
+const (
+	SinkConsole = "console"
+	SinkFile = "file"
+)
+
+// 日志格式
+const (
+	FormatText = "text"
+	FormatJson = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// LogSink 一个日志输出，每个输出可以使用不同的格式、最低级别与切割方式
+//
+// 示例：
+//	sinks:
+//	  - type: console
+//	    format: text
+//	    color: true
+//	  - type: file
+//	    filename: error.log
+//	    level: error
+//	    format: json
+//	    maxSize: 10MB
+//	    maxBackups: 5
+//	    compress: true
+type LogSink struct {
+	// 输出类型：console、file
+	Type string 			`json:"type" yaml:"type"`
+	// 日志格式：text、json、logfmt，console默认为text，file默认为json
+	Format string 			`json:"format,omitempty" yaml:"format,omitempty"`
+	// 最低级别，低于此级别的日志不输出，未配置时输出所有日志
+	Level string 			`json:"level,omitempty" yaml:"level,omitempty"`
+	// 是否使用颜色，只对console的text格式有效
+	Color bool 				`json:"color,omitempty" yaml:"color,omitempty"`
+	// 日志文件名，相对于logger.path，type为file时必须配置
+	Filename string 		`json:"filename,omitempty" yaml:"filename,omitempty"`
+	// 文件超过此大小时切割，如 10MB，未配置时不按大小切割
+	MaxSize string 			`json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+	// 切割的时间间隔，如 24h，未配置时不按时间切割
+	RotationTime string 	`json:"rotationTime,omitempty" yaml:"rotationTime,omitempty"`
+	// 切割后的文件最多保留的个数，0表示不限制
+	MaxBackups int 			`json:"maxBackups,omitempty" yaml:"maxBackups,omitempty"`
+	// 切割后的文件最长保留的时间，如 1440h，未配置时不限制
+	MaxAge string 			`json:"maxAge,omitempty" yaml:"maxAge,omitempty"`
+	// 是否使用gzip压缩切割后的文件
+	Compress bool 			`json:"compress,omitempty" yaml:"compress,omitempty"`
+}
+
+// FormatOrDefault 日志格式，未配置时console为text，file为json
+func (s *LogSink) FormatOrDefault() string{
+	if s.Format!="" {
+		return strings.ToLower(s.Format)
+	}
+	if s.Type==SinkConsole {
+		return FormatText
+	}
+	return FormatJson
+}
+
+// DefaultSinks 未配置sinks时使用的输出：控制台与按时间切割的filename文件
+func (l *Logger) DefaultSinks() []LogSink{
+	return []LogSink{
+		{Type: SinkConsole, Format: FormatText},
+		{Type: SinkFile, Format: FormatJson, Filename: l.Filename, RotationTime: l.RotationTime, MaxAge: l.MaxAge},
+	}
+}
+
+// EffectiveSinks 生效的日志输出，未配置sinks时为 DefaultSinks
+func (l *Logger) EffectiveSinks() []LogSink{
+	if len(l.Sinks)==0 {
+		return l.DefaultSinks()
+	}
+	return l.Sinks
+}
+
+// ModuleLevels 解析各模块的日志级别
+// return
+//    map[string]string: 模块名与日志级别
+//    error: 格式错误
+func (l *Logger) ModuleLevels() (map[string]string, error){
+	levels := make(map[string]string)
+	for _, item := range strings.Split(l.Modules, ",") {
+		item = strings.TrimSpace(item)
+		if item=="" {
+			continue
+		}
+		idx := strings.Index(item, "=")
+		if idx<=0 {
+			return nil, fmt.Errorf("格式错误，应为 模块=级别: %s", item)
+		}
+		levels[strings.TrimSpace(item[:idx])] = strings.TrimSpace(item[idx+1:])
+	}
+	return levels, nil
 }
 
 type EnvironmentConfig struct {
 	Profile string		`json:"profile" yaml:"profile"`
+	// 继承的环境，未配置的值从父环境中获取
+	Extends string		`json:"extends,omitempty" yaml:"extends,omitempty"`
 
-	Database Database 	`json:"database" yaml:"database"`
+	Database Database 	`json:"database" yaml:"database,omitempty"`
 
-	Server Server 		`json:"server" yaml:"server"`
+	Server Server 		`json:"server" yaml:"server,omitempty"`
 
-	Logger Logger 		`json:"logger" yaml:"logger"`
+	Logger Logger 		`json:"logger" yaml:"logger,omitempty"`
 }
 
 type ConfigContent struct {
+	// 配置文件的版本，旧版本的配置文件加载时会自动升级
+	Version int 				`json:"version,omitempty" yaml:"version,omitempty"`
 	Environment string 			`json:"environment" yaml:"environment"`
+	// 所有环境共享的默认配置
+	Defaults EnvironmentConfig		`json:"defaults" yaml:"defaults,omitempty"`
 	Configurations []EnvironmentConfig		`json:"configurations" yaml:"configurations"`
 }
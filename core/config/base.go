@@ -1,5 +1,13 @@
 package config
 
+import "errors"
+
+// DefaultProfile 没有配置文件且未指定环境时使用的环境
+const DefaultProfile = "dev"
+
+// ErrConfigNotFound 未找到应用配置文件，可用 errors.Is 判断
+var ErrConfigNotFound = errors.New("configuration file not found")
+
 type LoadState int
 
 const (
@@ -13,4 +21,10 @@ const (
 	ApplicationEnvVar = "TRAN_TICKET_APP"
 	// api.yml文件路径在环境中的变量名
 	ApiEnvVar = "TRAN_TICKET_API"
+	// 存放拆分的api配置文件的目录名，位于api.yml所在目录中
+	ApiDir = "api.d"
+	// 覆盖配置文件中environment的环境变量名
+	ProfileEnvVar = "TRAN_TICKET_PROFILE"
+	// 覆盖配置值的环境变量名前缀，如 TRAN_TICKET_SERVER_PORT 覆盖 server.port
+	EnvVarPrefix = "TRAN_TICKET_"
 )
\ No newline at end of file
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Decoder 将配置文件内容解析到v中，v为结构体指针
+type Decoder func(data []byte, v interface{}) error
+
+// Encoder 将v转换为配置文件内容，用于升级旧版本的配置文件后写回
+type Encoder func(v interface{}) ([]byte, error)
+
+type decoderRegistry struct {
+	lock sync.RWMutex
+	//扩展名的注册顺序，也是查找配置文件的顺序
+	exts []string
+	decoders map[string]Decoder
+	encoders map[string]Encoder
+}
+
+var registry = &decoderRegistry{decoders: make(map[string]Decoder), encoders: make(map[string]Encoder)}
+
+func init(){
+	RegisterDecoder(".yml", yaml.Unmarshal)
+	RegisterDecoder(".yaml", yaml.Unmarshal)
+	RegisterDecoder(".json", json.Unmarshal)
+	RegisterDecoder(".toml", decodeToml)
+	RegisterDecoder(".env", decodeDotenv)
+
+	RegisterEncoder(".yml", yaml.Marshal)
+	RegisterEncoder(".yaml", yaml.Marshal)
+	RegisterEncoder(".json", encodeJson)
+	RegisterEncoder(".toml", encodeToml)
+}
+
+// RegisterDecoder 注册配置文件解析器，application与api配置均通过扩展名选择解析器
+// 查找配置文件时按注册顺序依次尝试，重复注册同一扩展名会替换解析器但不改变顺序
+// param
+//    ext: 扩展名，如 .yml
+//    decoder: 解析器
+func RegisterDecoder(ext string, decoder Decoder){
+	ext = normalizeExt(ext)
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.decoders[ext]; !ok {
+		registry.exts = append(registry.exts, ext)
+	}
+	registry.decoders[ext] = decoder
+}
+
+// RegisterEncoder 注册配置文件的编码器，没有编码器的配置文件升级后只在内存中生效，不会写回文件
+func RegisterEncoder(ext string, encoder Encoder){
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.encoders[normalizeExt(ext)] = encoder
+}
+
+// EncoderFor 根据文件扩展名获取编码器
+func EncoderFor(path string) (Encoder, bool){
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	encoder, ok := registry.encoders[normalizeExt(filepath.Ext(path))]
+	return encoder, ok
+}
+
+// DecoderFor 根据文件扩展名获取解析器
+func DecoderFor(path string) (Decoder, bool){
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	decoder, ok := registry.decoders[normalizeExt(filepath.Ext(path))]
+	return decoder, ok
+}
+
+// Extensions 已注册的扩展名，按注册顺序排列
+func Extensions() []string{
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	exts := make([]string, len(registry.exts))
+	copy(exts, registry.exts)
+	return exts
+}
+
+// decodeFile 使用文件扩展名对应的解析器解析内容
+func decodeFile(path string, data []byte, v interface{}) error{
+	decoder, ok := DecoderFor(path)
+	if !ok {
+		return unsupportedFileError(path)
+	}
+	return decoder(data, v)
+}
+
+// configFileNames 按解析器的注册顺序生成配置文件名，如 application.yml、application.yaml
+func configFileNames(base string) []string{
+	exts := Extensions()
+	names := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		names = append(names, base + ext)
+	}
+	return names
+}
+
+func unsupportedFileError(path string) error{
+	return fmt.Errorf("不支持的配置文件类型: %s，支持的扩展名: %s", path, strings.Join(Extensions(), ", "))
+}
+
+func normalizeExt(ext string) string{
+	ext = strings.ToLower(ext)
+	if ext!="" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// remarshal 通过json将通用结构转换到v中，字段按json标签匹配且不区分大小写
+func remarshal(tree interface{}, v interface{}) error{
+	data, err := json.Marshal(tree)
+	if err!=nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func encodeJson(v interface{}) ([]byte, error){
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func encodeToml(v interface{}) ([]byte, error){
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err!=nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeToml(data []byte, v interface{}) error{
+	tree := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &tree); err!=nil {
+		return err
+	}
+	return remarshal(tree, v)
+}
+
+// decodeDotenv 解析dotenv格式，键以 . 分隔表示层级，数字表示数组下标，如：
+//	ENVIRONMENT=dev
+//	CONFIGURATIONS.0.PROFILE=dev
+//	CONFIGURATIONS.0.SERVER.PORT=8000
+func decodeDotenv(data []byte, v interface{}) error{
+	env, err := godotenv.Unmarshal(string(data))
+	if err!=nil {
+		return err
+	}
+	tree := make(map[string]interface{})
+	for key, value := range env {
+		node := tree
+		segments := strings.Split(key, ".")
+		for i, segment := range segments {
+			if i==len(segments)-1 {
+				node[segment] = value
+				break
+			}
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return remarshal(coerce(indexToSlice(tree), reflect.TypeOf(v)), v)
+}
+
+// coerce dotenv中的值都是字符串，按v中字段的类型将字符串转换为数字或布尔值，如 VERSION=1、API.0.RETRIES=2
+// 无法转换的值保持不变，由json解析时返回错误
+func coerce(node interface{}, t reflect.Type) interface{}{
+	for t!=nil && t.Kind()==reflect.Ptr {
+		t = t.Elem()
+	}
+	if t==nil {
+		return node
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			switch t.Kind() {
+			case reflect.Struct:
+				if field, ok := jsonField(t, key); ok {
+					v[key] = coerce(item, field)
+				}
+			case reflect.Map:
+				v[key] = coerce(item, t.Elem())
+			}
+		}
+		return v
+	case []interface{}:
+		if t.Kind()==reflect.Slice || t.Kind()==reflect.Array {
+			for i, item := range v {
+				v[i] = coerce(item, t.Elem())
+			}
+		}
+		return v
+	case string:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err==nil {
+				return i
+			}
+		case reflect.Float32, reflect.Float64:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err==nil {
+				return f
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err==nil {
+				return b
+			}
+		}
+	}
+	return node
+}
+
+// jsonField 按json的规则查找字段的类型，字段名不区分大小写，包含嵌入结构体的字段
+func jsonField(t reflect.Type, key string) (reflect.Type, bool){
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name=="-" {
+			continue
+		}
+		if field.Anonymous && name=="" {
+			embedded := field.Type
+			if embedded.Kind()==reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind()==reflect.Struct {
+				if ft, ok := jsonField(embedded, key); ok {
+					return ft, true
+				}
+				continue
+			}
+		}
+		if field.PkgPath!="" {
+			continue
+		}
+		if name=="" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// indexToSlice 将键全部为数字的map转换为按下标排列的切片
+func indexToSlice(node interface{}) interface{}{
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+	indexes := make([]int, 0, len(m))
+	for k, v := range m {
+		m[k] = indexToSlice(v)
+		if i, err := strconv.Atoi(k); err==nil {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(m)==0 || len(indexes)!=len(m) {
+		return m
+	}
+	sort.Ints(indexes)
+	list := make([]interface{}, 0, len(indexes))
+	for _, i := range indexes {
+		list = append(list, m[strconv.Itoa(i)])
+	}
+	return list
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// fieldVisitor 遍历配置字段时调用的函数
+//    key: 以 . 连接的yaml字段名，如 server.port
+//    field: 字段的值，可直接修改
+type fieldVisitor func(key string, field reflect.Value)
+
+// walkStringFields 遍历结构体中所有的字符串字段，嵌套的结构体会递归遍历
+// param
+//    v: 结构体指针
+//    visitor: 遍历到每个字符串字段时调用
+func walkStringFields(v interface{}, visitor fieldVisitor){
+	walkValue(reflect.ValueOf(v).Elem(), "", reflect.String, visitor)
+}
+
+// walkListFields 遍历结构体中所有的切片字段，如 logger.sinks，列表作为一个整体参与合并，不会遍历其中的元素
+func walkListFields(v interface{}, visitor fieldVisitor){
+	walkValue(reflect.ValueOf(v).Elem(), "", reflect.Slice, visitor)
+}
+
+func walkValue(v reflect.Value, prefix string, kind reflect.Kind, visitor fieldVisitor){
+	t := v.Type()
+	for i:=0; i<t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := fieldName(sf)
+		if name=="-" {
+			continue
+		}
+		key := name
+		if prefix!="" {
+			key = prefix + "." + name
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case kind:
+			visitor(key, field)
+		case reflect.Struct:
+			walkValue(field, key, kind, visitor)
+		}
+	}
+}
+
+// fieldName 字段在配置文件中的名称，取自yaml标签
+func fieldName(sf reflect.StructField) string{
+	tag := sf.Tag.Get("yaml")
+	if tag=="" {
+		return sf.Name
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// envVarName 将字段名转换成环境变量名，如 logger.maxAge 转换为 TRAN_TICKET_LOGGER_MAX_AGE
+func envVarName(key string) string{
+	var name strings.Builder
+	name.WriteString(EnvVarPrefix)
+	for i, r := range key {
+		if r=='.' {
+			name.WriteRune('_')
+			continue
+		}
+		if i>0 && unicode.IsUpper(r) {
+			name.WriteRune('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
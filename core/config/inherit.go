@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultsProfile 配置文件中 defaults 节点在来源信息中使用的名称
+const DefaultsProfile = "defaults"
+
+// isMetaField 描述环境本身而不是配置值的字段，不参与继承与覆盖
+func isMetaField(key string) bool{
+	return key=="profile" || key=="extends"
+}
+
+// inheritChain 获取环境的继承链，第一个元素是最顶层的父环境，最后一个元素是env本身
+// param
+//    content: 配置文件内容
+//    env: 环境名
+// return
+//    []EnvironmentConfig: 继承链，env不存在时为空
+//    error: 父环境不存在或继承存在循环时返回错误
+func inheritChain(content *ConfigContent, file string, env string) ([]EnvironmentConfig, error){
+	profiles := make(map[string]EnvironmentConfig, len(content.Configurations))
+	for _, config := range content.Configurations {
+		profiles[config.Profile] = config
+	}
+	var chain []EnvironmentConfig
+	var visited []string
+	name := env
+	for name!="" {
+		for _, v := range visited {
+			if v==name {
+				path := strings.Join(append(visited, name), " -> ")
+				return nil, singleError(file, fmt.Sprintf("configurations[%s].extends", visited[len(visited)-1]),
+					"环境继承存在循环: %s", path)
+			}
+		}
+		config, ok := profiles[name]
+		if !ok {
+			if len(visited)==0 {
+				return nil, nil
+			}
+			return nil, singleError(file, fmt.Sprintf("configurations[%s].extends", visited[len(visited)-1]),
+				"继承的环境 %s 不存在", name)
+		}
+		visited = append(visited, name)
+		chain = append([]EnvironmentConfig{config}, chain...)
+		name = config.Extends
+	}
+	return chain, nil
+}
+
+// inheritEnvironmentConfig 将 defaults 与继承链上的环境配置依次深度合并，子环境中非空的值覆盖父环境，
+// 列表不合并元素，子环境中非空的列表整体替换父环境的列表
+// return
+//    EnvironmentConfig: 合并后的环境配置
+//    map[string]string: 每个配置值取自哪个环境，defaults 中的值为 DefaultsProfile
+//    bool: env是否存在
+//    error: 父环境不存在或继承存在循环时返回错误
+func inheritEnvironmentConfig(content *ConfigContent, file string, env string) (EnvironmentConfig, map[string]string, bool, error){
+	origins := make(map[string]string)
+	chain, err := inheritChain(content, file, env)
+	if err!=nil || len(chain)==0 {
+		return EnvironmentConfig{}, origins, false, err
+	}
+	merged := EnvironmentConfig{}
+	mergeEnvironmentConfig(&merged, content.Defaults, DefaultsProfile, origins)
+	for _, config := range chain {
+		mergeEnvironmentConfig(&merged, config, config.Profile, origins)
+	}
+	merged.Profile = env
+	merged.Extends = chain[len(chain)-1].Extends
+	return merged, origins, true, nil
+}
+
+func mergeEnvironmentConfig(dst *EnvironmentConfig, src EnvironmentConfig, profile string, origins map[string]string){
+	values := make(map[string]string)
+	walkStringFields(&src, func(key string, field reflect.Value) {
+		values[key] = field.String()
+	})
+	walkStringFields(dst, func(key string, field reflect.Value) {
+		if isMetaField(key) {
+			return
+		}
+		if v := values[key]; v!="" {
+			field.SetString(v)
+			origins[key] = profile
+		}
+	})
+	lists := make(map[string]reflect.Value)
+	walkListFields(&src, func(key string, field reflect.Value) {
+		lists[key] = field
+	})
+	walkListFields(dst, func(key string, field reflect.Value) {
+		if v := lists[key]; v.Len()>0 {
+			field.Set(v)
+			origins[key] = profile
+		}
+	})
+}
+
+func singleError(location, field, format string, args ...interface{}) error{
+	v := &ValidationError{}
+	v.add(location, field, format, args...)
+	return v
+}
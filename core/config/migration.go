@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/gookit/color"
+	"gopkg.in/yaml.v2"
+	"os"
+)
+
+// ApplicationMigrations 应用配置文件的升级函数，修改配置文件的结构时在此注册，如：
+//	func init(){
+//		ApplicationMigrations.Register(1, "logger.maxAge 改为小时数", func(doc map[string]interface{}) error { ... })
+//	}
+var ApplicationMigrations = migrate.NewRegistry("application")
+
+// SetMigrations 设置升级配置文件使用的升级函数，需要在Load之前调用，默认为 ApplicationMigrations
+func (c *ApplicationConfig) SetMigrations(registry *migrate.Registry) *ApplicationConfig{
+	c.migrations = registry
+	return c
+}
+
+// SetRewriteMigrated 升级旧版本的配置文件后是否备份原文件并写入升级后的内容，默认只在内存中升级
+// 写回的文件会丢失注释与原有的顺序，只应在服务启动时加载配置开启，config show 与热加载不修改配置文件
+func (c *ApplicationConfig) SetRewriteMigrated(rewrite bool) *ApplicationConfig{
+	c.rewriteMigrated = rewrite
+	return c
+}
+
+// Migrations 加载配置文件时执行的升级函数，配置文件已是当前版本时为空
+func (c *ApplicationConfig) Migrations() []migrate.Migration{
+	return c.migrated
+}
+
+// migrateFile 将旧版本的配置文件升级到当前版本，开启 SetRewriteMigrated 时原文件备份后写入升级后的内容，
+// 未开启、没有编码器或写入失败时只在内存中升级
+// return
+//    []byte: 升级后的文件内容，未升级时为data
+//    Decoder: 解析升级后内容的解析器
+//    error: 解析或升级失败
+func (c *ApplicationConfig) migrateFile(path string, data []byte) ([]byte, Decoder, error){
+	decoder, ok := DecoderFor(path)
+	if !ok {
+		return nil, nil, unsupportedFileError(path)
+	}
+	tree := make(map[string]interface{})
+	if err := decoder(data, &tree); err!=nil {
+		return nil, nil, err
+	}
+	doc := migrate.Normalize(tree).(map[string]interface{})
+	version, err := migrate.Version(doc)
+	if err!=nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	applied, err := c.migrations.Migrate(doc)
+	if err!=nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(applied)==0 {
+		return data, decoder, nil
+	}
+	c.migrated = applied
+	for _, m := range applied {
+		color.Println("<light_green>migrate config:</>", path, m)
+	}
+
+	encoder, ok := EncoderFor(path)
+	if !ok || !c.rewriteMigrated {
+		if !ok {
+			color.Println("<yellow>config file can not be rewritten, migrated in memory only:</>", path)
+		}
+		migrated, err := yaml.Marshal(doc)
+		return migrated, yaml.Unmarshal, err
+	}
+	migrated, err := encoder(doc)
+	if err!=nil {
+		return nil, nil, err
+	}
+	if err = rewriteFile(path, version, migrated); err!=nil {
+		color.Println("<yellow>rewrite migrated config fail, migrated in memory only:</>", path, err)
+	}
+	return migrated, decoder, nil
+}
+
+// rewriteFile 备份原文件后写入升级后的内容
+func rewriteFile(path string, version int, data []byte) error{
+	info, err := os.Stat(path)
+	if err!=nil {
+		return err
+	}
+	backup, err := migrate.Backup(path, version)
+	if err!=nil {
+		return err
+	}
+	color.Println("<light_green>backup config to:</>", backup)
+	return core.WriteFileAtomic(path, data, info.Mode().Perm())
+}
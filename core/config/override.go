@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core/paths"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Layer 配置值所在的层，后面的层会覆盖前面的层：默认值 < 配置文件 < 环境变量 < 命令行参数
+type Layer string
+
+const (
+	// LayerDefault 内置默认值
+	LayerDefault Layer = "default"
+	// LayerFile 配置文件
+	LayerFile Layer = "file"
+	// LayerEnv 环境变量
+	LayerEnv Layer = "env"
+	// LayerFlag 命令行参数
+	LayerFlag Layer = "flag"
+)
+
+// Source 配置值的来源
+type Source struct {
+	Layer Layer 		`json:"layer" yaml:"layer"`
+	// 来源位置：配置文件路径、环境变量名或命令行参数名
+	Location string 	`json:"location" yaml:"location"`
+	// 来自配置文件时，值所在的环境，defaults 节点中的值为 DefaultsProfile
+	Profile string 		`json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+// String 输出如 [file config/application.yml, dev]、[env TRAN_TICKET_SERVER_PORT] 形式的来源描述
+func (s Source) String() string{
+	switch s.Layer {
+	case LayerFile:
+		if s.Profile!="" {
+			return fmt.Sprintf("[file %s, %s]", s.Location, s.Profile)
+		}
+		return fmt.Sprintf("[file %s]", s.Location)
+	case LayerDefault:
+		return "[default]"
+	case "":
+		return ""
+	default:
+		return fmt.Sprintf("[%s %s]", s.Layer, s.Location)
+	}
+}
+
+// DefaultEnvironmentConfig 内置的默认配置，配置文件、环境变量与命令行参数均未设置时使用
+func DefaultEnvironmentConfig() EnvironmentConfig{
+	return EnvironmentConfig{
+		Server: Server{
+			Port: "8000",
+			AccessLog: AccessLog{
+				Format: AccessLogFields,
+				Skip: "/assets/*,/favicon.ico",
+				SlowThreshold: "1s",
+			},
+		},
+		Logger: Logger{
+			Level: "info",
+			Path: paths.LogDir(),
+			Filename: "desktop-app",
+			MaxAge: "1440h",
+			RotationTime: "24h",
+		},
+	}
+}
+
+// flagValues 解析 --server.port=8000 或 --server.port 8000 形式的命令行参数，不认识的参数会被忽略
+func flagValues(args []string) map[string]string{
+	values := make(map[string]string)
+	for i:=0; i<len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || len(arg)==2 {
+			continue
+		}
+		name := arg[2:]
+		if idx := strings.Index(name, "="); idx>=0 {
+			values[name[:idx]] = name[idx+1:]
+			continue
+		}
+		if i+1<len(args) && !strings.HasPrefix(args[i+1], "-") {
+			values[name] = args[i+1]
+			i++
+		}
+	}
+	return values
+}
+
+func lookupFlag(flags map[string]string, key string) (string, bool){
+	if v, ok := flags[key]; ok {
+		return v, true
+	}
+	for name, v := range flags {
+		if strings.EqualFold(name, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// profileOverride 从命令行参数 --profile 或环境变量中获取要使用的环境，未设置时返回空字符串
+func (c *ApplicationConfig) profileOverride() (string, Source){
+	if profile, ok := lookupFlag(flagValues(c.args), "profile"); ok && profile!="" {
+		return profile, Source{Layer: LayerFlag, Location: "--profile"}
+	}
+	if profile := os.Getenv(ProfileEnvVar); profile!="" {
+		return profile, Source{Layer: LayerEnv, Location: ProfileEnvVar}
+	}
+	return "", Source{}
+}
+
+// applyLayers 按 默认值、配置文件、环境变量、命令行参数 的顺序合并配置，并记录每个配置值的来源
+//    fileConfig: 配置文件中合并了继承关系的环境配置
+//    origins: 配置文件中每个值所在的环境
+//    file: 配置文件路径
+func (c *ApplicationConfig) applyLayers(fileConfig EnvironmentConfig, origins map[string]string, file string){
+	resolved := DefaultEnvironmentConfig()
+	resolved.Profile = fileConfig.Profile
+	resolved.Extends = fileConfig.Extends
+	flags := flagValues(c.args)
+
+	fileValues := make(map[string]string)
+	walkStringFields(&fileConfig, func(key string, field reflect.Value) {
+		fileValues[key] = field.String()
+	})
+	walkStringFields(&resolved, func(key string, field reflect.Value) {
+		if isMetaField(key) {
+			return
+		}
+		source := Source{Layer: LayerDefault}
+		if v := fileValues[key]; v!="" {
+			field.SetString(v)
+			source = Source{Layer: LayerFile, Location: file, Profile: origins[key]}
+		}
+		envName := envVarName(key)
+		if v := os.Getenv(envName); v!="" {
+			field.SetString(v)
+			source = Source{Layer: LayerEnv, Location: envName}
+		}
+		if v, ok := lookupFlag(flags, key); ok {
+			field.SetString(v)
+			source = Source{Layer: LayerFlag, Location: "--" + key}
+		}
+		c.sources[key] = source
+	})
+	//列表只能在配置文件中设置
+	fileLists := make(map[string]reflect.Value)
+	walkListFields(&fileConfig, func(key string, field reflect.Value) {
+		fileLists[key] = field
+	})
+	walkListFields(&resolved, func(key string, field reflect.Value) {
+		source := Source{Layer: LayerDefault}
+		if v := fileLists[key]; v.Len()>0 {
+			field.Set(v)
+			source = Source{Layer: LayerFile, Location: file, Profile: origins[key]}
+		}
+		c.sources[key] = source
+	})
+	c.EnvironmentConfig = resolved
+}
+
+// SetArgs 设置用于覆盖配置的命令行参数，默认使用 os.Args[1:]，需要在 Load 之前调用
+func (c *ApplicationConfig) SetArgs(args []string) *ApplicationConfig{
+	c.args = args
+	return c
+}
+
+// Source 获取配置值的来源
+//    key: 以 . 连接的字段名，如 server.port、logger.level，environment 表示当前环境
+func (c *ApplicationConfig) Source(key string) Source{
+	return c.sources[key]
+}
+
+// Sources 获取所有配置值的来源
+func (c *ApplicationConfig) Sources() map[string]Source{
+	sources := make(map[string]Source, len(c.sources))
+	for k, v := range c.sources {
+		sources[k] = v
+	}
+	return sources
+}
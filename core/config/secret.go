@@ -0,0 +1,148 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/paths"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// EncryptedPrefix 加密配置值的前缀，如 url: 'enc:q5k...'
+const EncryptedPrefix = "enc:"
+
+// 密钥长度，使用AES-256
+const secretKeySize = 32
+
+// SecretKeyFile 加密密钥文件的路径，位于用户数据目录中
+func SecretKeyFile() string{
+	return filepath.Join(paths.DataDir(), "secret.key")
+}
+
+// IsEncrypted 配置值是否为加密的值
+func IsEncrypted(value string) bool{
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Encrypt 加密配置值，返回 enc: 开头的密文，密钥文件不存在时会生成新的密钥
+func Encrypt(plain string) (string, error){
+	key, err := secretKey(true)
+	if err!=nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err!=nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err!=nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 enc: 开头的配置值
+func Decrypt(value string) (string, error){
+	if !IsEncrypted(value) {
+		return "", errors.New("不是加密的配置值，应以 " + EncryptedPrefix + " 开头")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err!=nil {
+		return "", fmt.Errorf("密文格式错误: %w", err)
+	}
+	key, err := secretKey(false)
+	if err!=nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err!=nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("密文长度错误")
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err!=nil {
+		return "", fmt.Errorf("解密失败，密钥与加密时使用的不一致: %s", SecretKeyFile())
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error){
+	block, err := aes.NewCipher(key)
+	if err!=nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretKey 读取密钥文件
+//    create: 密钥文件不存在时是否生成新的密钥
+func secretKey(create bool) ([]byte, error){
+	file := SecretKeyFile()
+	if !core.IsExists(file) {
+		if !create {
+			return nil, fmt.Errorf("密钥文件不存在: %s", file)
+		}
+		return createSecretKey(file)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err!=nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err!=nil || len(key)!=secretKeySize {
+		return nil, fmt.Errorf("密钥文件格式错误: %s", file)
+	}
+	return key, nil
+}
+
+func createSecretKey(file string) ([]byte, error){
+	key := make([]byte, secretKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err!=nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err!=nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	if err := ioutil.WriteFile(file, []byte(encoded + "\n"), 0600); err!=nil {
+		return nil, fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	return key, nil
+}
+
+// decryptSecrets 解密所有 enc: 开头的配置值，解密失败的配置项会汇总在返回的 *ValidationError 中
+func (c *ApplicationConfig) decryptSecrets() error{
+	v := &ValidationError{}
+	walkStringFields(&c.EnvironmentConfig, func(key string, field reflect.Value) {
+		if !IsEncrypted(field.String()) {
+			return
+		}
+		plain, err := Decrypt(field.String())
+		if err!=nil {
+			c.addFieldError(v, key, "解密失败: %s", err)
+			return
+		}
+		field.SetString(plain)
+		c.encrypted[key] = true
+	})
+	return v.orNil()
+}
+
+// Encrypted 配置值在配置文件、环境变量或命令行参数中是否为加密的值
+//    key: 以 . 连接的字段名，如 database.url
+func (c *ApplicationConfig) Encrypted(key string) bool{
+	return c.encrypted[key]
+}
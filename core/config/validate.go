@@ -0,0 +1,347 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FieldError 单个配置项的校验错误
+type FieldError struct {
+	// 配置所在位置：配置文件路径、环境变量名或命令行参数名
+	Location string
+	// 配置项，如 configurations[dev].server.port
+	Field string
+	// 错误描述
+	Message string
+}
+
+func (e *FieldError) Error() string{
+	if e.Location=="" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Location, e.Field, e.Message)
+}
+
+// ValidationError 配置校验错误，汇总了校验过程中发现的所有问题
+type ValidationError struct {
+	Errors []*FieldError
+	// 不影响加载的问题，如日志目录不可写时会改用备用目录
+	Warnings []*FieldError
+}
+
+func (v *ValidationError) Error() string{
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("配置校验失败，共%d个错误:", len(v.Errors)))
+	for _, e := range v.Errors {
+		msg.WriteString("\n  - ")
+		msg.WriteString(e.Error())
+	}
+	for _, w := range v.Warnings {
+		msg.WriteString("\n  - 警告: ")
+		msg.WriteString(w.Error())
+	}
+	return msg.String()
+}
+
+func (v *ValidationError) add(location, field, format string, args ...interface{}){
+	v.Errors = append(v.Errors, &FieldError{
+		Location: location,
+		Field: field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *ValidationError) warn(location, field, format string, args ...interface{}){
+	v.Warnings = append(v.Warnings, &FieldError{
+		Location: location,
+		Field: field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// orNil 没有错误时返回nil，避免返回值为nil的*ValidationError导致 err!=nil 判断出错
+func (v *ValidationError) orNil() error{
+	if len(v.Errors)==0 {
+		return nil
+	}
+	return v
+}
+
+// Validate 校验配置，发现的所有问题会汇总在返回的 *ValidationError 中
+// 只有警告时返回nil，警告可通过 Warnings 获取
+func (c *ApplicationConfig) Validate() error{
+	v := &ValidationError{}
+	if !c.profileFound {
+		envSource := c.sources["environment"]
+		v.add(envSource.Location, "environment", "未找到名为 %s 的环境配置", c.Environment)
+	}
+
+	port := c.Server.Port
+	if p, err := strconv.Atoi(port); err!=nil || p<1 || p>65535 {
+		c.addFieldError(v, "server.port", "端口必须是1-65535之间的数字: %s", port)
+	}
+	c.validateAccessLog(v)
+	if _, err := logrus.ParseLevel(c.Logger.Level); err!=nil {
+		c.addFieldError(v, "logger.level", "无法识别的日志级别: %s", c.Logger.Level)
+	}
+	if levels, err := c.Logger.ModuleLevels(); err!=nil {
+		c.addFieldError(v, "logger.modules", "%s", err)
+	}else{
+		modules := make([]string, 0, len(levels))
+		for module := range levels {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+		for _, module := range modules {
+			if _, err := logrus.ParseLevel(levels[module]); err!=nil {
+				c.addFieldError(v, "logger.modules", "无法识别的日志级别: %s=%s", module, levels[module])
+			}
+		}
+	}
+	c.validateDuration(v, "logger.maxAge", c.Logger.MaxAge)
+	c.validateDuration(v, "logger.rotationTime", c.Logger.RotationTime)
+	if c.Logger.Filename=="" {
+		c.addFieldError(v, "logger.filename", "日志文件名不能为空")
+	}
+	for i, pattern := range c.Logger.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err!=nil {
+			c.addFieldError(v, fmt.Sprintf("logger.redact.patterns[%d]", i), "无法解析的正则表达式: %s, %s", pattern, err)
+		}
+	}
+	for i, sink := range c.Logger.Sinks {
+		c.validateSink(v, fmt.Sprintf("logger.sinks[%d]", i), sink)
+	}
+	//日志目录不可写时日志会改用备用目录，不影响启动；目录不存在时由 log.InitLog 创建，这里只检查能否在最近的上级目录中创建
+	if err := checkWritable(c.Logger.Path); err!=nil {
+		c.addFieldWarning(v, "logger.path", "日志目录不可写，将使用 %s: %s, %s", logFallbackDir(c.Logger.Path), c.Logger.Path, err)
+	}
+	c.warnings = v.Warnings
+	return v.orNil()
+}
+
+// Warnings 最近一次校验发现的警告，不影响配置的加载
+func (c *ApplicationConfig) Warnings() []*FieldError{
+	return c.warnings
+}
+
+func (c *ApplicationConfig) validateAccessLog(v *ValidationError){
+	accessLog := c.Server.AccessLog
+	if strings.TrimSpace(accessLog.Format)=="" {
+		c.addFieldError(v, "server.accessLog.format", "请求日志格式不能为空，可选值: %s, %s 或自定义模板", AccessLogFields, AccessLogCombined)
+	}
+	for _, pattern := range accessLog.SkipPatterns() {
+		if _, err := path.Match(pattern, ""); err!=nil {
+			c.addFieldError(v, "server.accessLog.skip", "无法解析的路径: %s", pattern)
+		}
+	}
+	if accessLog.SlowThreshold!="" {
+		c.validateDuration(v, "server.accessLog.slowThreshold", accessLog.SlowThreshold)
+	}
+	if accessLog.BodySize!="" {
+		if _, err := core.ParseSize(accessLog.BodySize); err!=nil {
+			c.addFieldError(v, "server.accessLog.bodySize", "%s", err)
+		}
+	}
+}
+
+func (c *ApplicationConfig) validateSink(v *ValidationError, key string, sink LogSink){
+	switch sink.Type {
+	case SinkConsole:
+	case SinkFile:
+		if sink.Filename=="" {
+			c.addFieldError(v, key + ".filename", "日志文件名不能为空")
+		}
+	default:
+		c.addFieldError(v, key + ".type", "不支持的日志输出: %s，可选值: %s, %s", sink.Type, SinkConsole, SinkFile)
+	}
+	switch sink.FormatOrDefault() {
+	case FormatText, FormatJson, FormatLogfmt:
+	default:
+		c.addFieldError(v, key + ".format", "不支持的日志格式: %s，可选值: %s, %s, %s", sink.Format, FormatText, FormatJson, FormatLogfmt)
+	}
+	if sink.Level!="" {
+		if _, err := logrus.ParseLevel(sink.Level); err!=nil {
+			c.addFieldError(v, key + ".level", "无法识别的日志级别: %s", sink.Level)
+		}
+	}
+	if sink.MaxSize!="" {
+		if size, err := core.ParseSize(sink.MaxSize); err!=nil {
+			c.addFieldError(v, key + ".maxSize", "%s", err)
+		}else if size<=0 {
+			c.addFieldError(v, key + ".maxSize", "文件大小必须大于0: %s", sink.MaxSize)
+		}
+	}
+	if sink.RotationTime!="" {
+		c.validateDuration(v, key + ".rotationTime", sink.RotationTime)
+	}
+	if sink.MaxAge!="" {
+		c.validateDuration(v, key + ".maxAge", sink.MaxAge)
+	}
+	if sink.MaxBackups<0 {
+		c.addFieldError(v, key + ".maxBackups", "保留的文件个数不能小于0: %d", sink.MaxBackups)
+	}
+}
+
+func (c *ApplicationConfig) validateDuration(v *ValidationError, key, value string){
+	duration, err := time.ParseDuration(value)
+	if err!=nil {
+		c.addFieldError(v, key, "无法解析的时间间隔: %s，应使用如 24h、30m 的格式", value)
+		return
+	}
+	if duration<=0 {
+		c.addFieldError(v, key, "时间间隔必须大于0: %s", value)
+	}
+}
+
+// addFieldError 根据配置值的来源记录错误位置
+func (c *ApplicationConfig) addFieldError(v *ValidationError, key, format string, args ...interface{}){
+	location, field := c.fieldOf(key)
+	v.add(location, field, format, args...)
+}
+
+func (c *ApplicationConfig) addFieldWarning(v *ValidationError, key, format string, args ...interface{}){
+	location, field := c.fieldOf(key)
+	v.warn(location, field, format, args...)
+}
+
+// fieldOf 获取配置项的来源位置与在配置文件中的完整名称
+func (c *ApplicationConfig) fieldOf(key string) (string, string){
+	source, ok := c.sources[key]
+	if idx := strings.IndexByte(key, '['); !ok && idx>0 {
+		//列表中的元素使用列表的来源，如 logger.sinks[0].level
+		source = c.sources[key[:idx]]
+	}
+	field := key
+	if source.Layer==LayerFile {
+		field = fmt.Sprintf("configurations[%s].%s", source.Profile, key)
+		if source.Profile==DefaultsProfile {
+			field = DefaultsProfile + "." + key
+		}
+	}
+	location := source.Location
+	if source.Layer==LayerDefault {
+		location = "默认值"
+	}
+	return location, field
+}
+
+// checkWritable 检查目录是否可写，校验时不创建任何目录，目录不存在时检查最近的已存在的上级目录
+func checkWritable(dir string) error{
+	if dir=="" {
+		return fmt.Errorf("目录不能为空")
+	}
+	existing, err := nearestExistingDir(dir)
+	if err!=nil {
+		return err
+	}
+	f, err := ioutil.TempFile(existing, ".writable")
+	if err!=nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// nearestExistingDir 从dir开始向上查找第一个已存在的目录，已存在的是文件时返回错误
+func nearestExistingDir(dir string) (string, error){
+	current, err := filepath.Abs(dir)
+	if err!=nil {
+		return "", err
+	}
+	for {
+		info, err := os.Stat(current)
+		if err==nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s 不是目录", current)
+			}
+			return current, nil
+		}
+		//上级目录是文件时继续向上查找，返回更明确的错误
+		if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent==current {
+			return "", err
+		}
+		current = parent
+	}
+}
+
+// logFallbackDir 日志目录不可写时改用的目录，与 log.InitLog 的选择顺序一致
+func logFallbackDir(dir string) string{
+	if dir!="" && filepath.Clean(dir)==filepath.Clean(paths.LogDir()) {
+		return filepath.Join(os.TempDir(), paths.AppName, "log")
+	}
+	return paths.LogDir()
+}
+
+// Validate 校验api配置，api的id必须唯一且url不能为空，发现的所有问题会汇总在返回的 *ValidationError 中
+func (a *ApiConfig) Validate() error{
+	v := &ValidationError{}
+	ids := make(map[string]int)
+	for i, api := range a.Apis {
+		origin := a.originOf(i)
+		field := origin.field()
+		if api.Id=="" {
+			v.add(origin.file, field + ".id", "id不能为空")
+		}else if j, ok := ids[api.Id]; ok {
+			v.add(origin.file, field + ".id", "id与%s重复: %s", a.originOf(j).field(), api.Id)
+		}else{
+			ids[api.Id] = i
+		}
+		if api.Url=="" {
+			v.add(origin.file, field + ".url", "url不能为空")
+		}
+		if !validMethods[api.HttpMethod()] {
+			v.add(origin.file, field + ".method", "不支持的请求方法: %s", api.Method)
+		}
+		if api.Timeout!="" {
+			if d, err := time.ParseDuration(api.Timeout); err!=nil || d<=0 {
+				v.add(origin.file, field + ".timeout", "超时时间格式错误，应为大于0的时长，如 10s: %s", api.Timeout)
+			}
+		}
+		if api.Retries<0 {
+			v.add(origin.file, field + ".retries", "重试次数不能小于0: %d", api.Retries)
+		}
+		switch api.ResponseType() {
+		case ResponseText, ResponseJson, ResponseBytes:
+		default:
+			v.add(origin.file, field + ".response", "不支持的响应类型: %s，可选值: %s, %s, %s",
+				api.Response, ResponseText, ResponseJson, ResponseBytes)
+		}
+	}
+	return v.orNil()
+}
+
+var validMethods = map[string]bool{
+	http.MethodGet: true,
+	http.MethodHead: true,
+	http.MethodPost: true,
+	http.MethodPut: true,
+	http.MethodPatch: true,
+	http.MethodDelete: true,
+	http.MethodOptions: true,
+}
+
+// originOf 获取api所在的配置文件，未记录时使用主配置文件
+func (a *ApiConfig) originOf(i int) apiOrigin{
+	if i<len(a.origins) {
+		return a.origins[i]
+	}
+	return apiOrigin{file: a.file, index: i}
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gookit/color"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 文件变化后延迟重新加载的时间，编辑器保存文件时通常会连续触发多个事件
+const reloadDelay = time.Millisecond * 200
+
+// ApplicationChangeEvent 应用配置变化事件
+type ApplicationChangeEvent struct {
+	// 变化前的环境配置
+	Old EnvironmentConfig
+	// 变化后的环境配置
+	New EnvironmentConfig
+	// 变化后的完整配置
+	Config *ApplicationConfig
+}
+
+// ApiChangeEvent api配置变化事件
+type ApiChangeEvent struct {
+	// 变化前的api
+	Old []Api
+	// 变化后的api
+	New []Api
+	// 变化后的完整配置
+	Config *ApiConfig
+}
+
+// ApplicationChangeListener 应用配置变化的监听程序
+type ApplicationChangeListener func(event *ApplicationChangeEvent)
+
+// ApiChangeListener api配置变化的监听程序
+type ApiChangeListener func(event *ApiChangeEvent)
+
+// ReloadErrorListener 重新加载配置失败时的监听程序，此时仍然保留上一次加载成功的配置
+type ReloadErrorListener func(file string, err error)
+
+// NewWatcher 创建配置文件监视器，app与api必须是已经加载成功的配置
+//
+// 示例：
+//	watcher := config.NewWatcher(app, api).
+//		OnApplicationChange(func(event *config.ApplicationChangeEvent) { ... }).
+//		OnApiChange(func(event *config.ApiChangeEvent) { ... })
+//	if err := watcher.Start(); err!=nil { ... }
+//	defer watcher.Close()
+func NewWatcher(app *ApplicationConfig, api *ApiConfig) *Watcher{
+	return &Watcher{
+		app: app,
+		api: api,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Watcher 监视application.yml、api.yml与api.d目录，文件变化后重新解析并通知监听程序
+// 新的配置解析失败时会被丢弃，Application 与 Api 始终返回最后一次加载成功的配置
+type Watcher struct {
+	lock sync.RWMutex
+	app *ApplicationConfig
+	api *ApiConfig
+
+	appListeners []ApplicationChangeListener
+	apiListeners []ApiChangeListener
+	errListeners []ReloadErrorListener
+
+	watcher *fsnotify.Watcher
+	//等待重新加载的文件
+	timers map[string]*time.Timer
+	timerLock sync.Mutex
+	//各文件的定时器在不同的goroutine中重新加载，同一时间只执行一次重新加载，
+	//避免应用配置切换环境时与api文件的重新加载交错，使用过期的api配置覆盖新的配置
+	reloadLock sync.Mutex
+	done chan struct{}
+}
+
+// OnApplicationChange 添加应用配置变化的监听程序
+func (w *Watcher) OnApplicationChange(listener ApplicationChangeListener) *Watcher{
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.appListeners = append(w.appListeners, listener)
+	return w
+}
+
+// OnApiChange 添加api配置变化的监听程序
+func (w *Watcher) OnApiChange(listener ApiChangeListener) *Watcher{
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.apiListeners = append(w.apiListeners, listener)
+	return w
+}
+
+// OnError 添加重新加载失败的监听程序
+func (w *Watcher) OnError(listener ReloadErrorListener) *Watcher{
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.errListeners = append(w.errListeners, listener)
+	return w
+}
+
+// Application 最后一次加载成功的应用配置
+func (w *Watcher) Application() *ApplicationConfig{
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.app
+}
+
+// Api 最后一次加载成功的api配置
+func (w *Watcher) Api() *ApiConfig{
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.api
+}
+
+// Start 开始监视配置文件，监视的是配置文件所在的目录，以便兼容编辑器先删除再创建文件的保存方式
+func (w *Watcher) Start() error{
+	if w.watcher != nil {
+		return errors.New("配置文件监视器已启动，请不要重复调用Start")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err!=nil {
+		return err
+	}
+	dirs := make(map[string]bool)
+	for _, file := range w.files() {
+		dirs[filepath.Dir(file)] = true
+	}
+	if apiDir := w.Api().Dir(); apiDir!="" {
+		dirs[apiDir] = true
+	}
+	for dir := range dirs {
+		if err = watcher.Add(dir); err!=nil {
+			_ = watcher.Close()
+			return err
+		}
+	}
+	w.watcher = watcher
+	w.done = make(chan struct{})
+	go w.run()
+	return nil
+}
+
+// Close 停止监视配置文件
+func (w *Watcher) Close() error{
+	if w==nil || w.watcher==nil {
+		return nil
+	}
+	close(w.done)
+	w.timerLock.Lock()
+	for file, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, file)
+	}
+	w.timerLock.Unlock()
+	return w.watcher.Close()
+}
+
+func (w *Watcher) files() []string{
+	var files []string
+	if app := w.Application(); app!=nil && app.File()!="" {
+		files = append(files, app.File())
+	}
+	if api := w.Api(); api!=nil {
+		files = append(files, api.Files()...)
+	}
+	return files
+}
+
+// isApiFile 文件是否为已加载的api配置文件，或api.d目录中新增的配置文件与当前环境的api覆盖文件
+func (w *Watcher) isApiFile(file string) bool{
+	api := w.Api()
+	for _, f := range api.Files() {
+		if filepath.Clean(f)==file {
+			return true
+		}
+	}
+	if api.isProfileFile(file) {
+		return true
+	}
+	if api.Dir()=="" || filepath.Clean(api.Dir())!=filepath.Dir(file) {
+		return false
+	}
+	_, ok := DecoderFor(file)
+	return ok
+}
+
+func (w *Watcher) run(){
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+			w.schedule(filepath.Clean(event.Name))
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.fireError("", err)
+		}
+	}
+}
+
+// schedule 延迟重新加载文件，延迟期间的重复事件会被合并
+func (w *Watcher) schedule(file string){
+	if !w.isWatched(file) {
+		return
+	}
+	w.timerLock.Lock()
+	defer w.timerLock.Unlock()
+	if timer, ok := w.timers[file]; ok {
+		timer.Reset(reloadDelay)
+		return
+	}
+	w.timers[file] = time.AfterFunc(reloadDelay, func() {
+		w.timerLock.Lock()
+		delete(w.timers, file)
+		w.timerLock.Unlock()
+		w.reload(file)
+	})
+}
+
+func (w *Watcher) isWatched(file string) bool{
+	return filepath.Clean(w.Application().File())==file || w.isApiFile(file)
+}
+
+func (w *Watcher) reload(file string){
+	w.reloadLock.Lock()
+	defer w.reloadLock.Unlock()
+	if app := w.Application(); filepath.Clean(app.File())==file {
+		w.reloadApplication(app)
+	}
+	if w.isApiFile(file) {
+		w.reloadApi(w.Api())
+	}
+}
+
+func (w *Watcher) reloadApplication(current *ApplicationConfig){
+	fresh, err := current.Reload()
+	if err!=nil {
+		w.fireError(current.File(), err)
+		return
+	}
+	color.Println("<light_green>reload config from:</>", fresh.File())
+	w.lock.Lock()
+	w.app = fresh
+	listeners := w.appListeners
+	w.lock.Unlock()
+
+	event := &ApplicationChangeEvent{
+		Old: current.EnvironmentConfig,
+		New: fresh.EnvironmentConfig,
+		Config: fresh,
+	}
+	for _, listener := range listeners {
+		listener(event)
+	}
+	//api配置跟随应用配置的环境，环境变化后使用新环境的url与api
+	if api := w.Api(); api.Profile()==current.Environment && fresh.Environment!=current.Environment {
+		w.reloadApiAs(api, fresh.Environment)
+	}
+}
+
+func (w *Watcher) reloadApi(current *ApiConfig){
+	w.reloadApiAs(current, current.Profile())
+}
+
+func (w *Watcher) reloadApiAs(current *ApiConfig, profile string){
+	fresh, err := current.reloadAs(profile)
+	if err!=nil {
+		w.fireError(current.File(), err)
+		return
+	}
+	color.Println("<light_green>reload api from:</>", fresh.File())
+	w.lock.Lock()
+	w.api = fresh
+	listeners := w.apiListeners
+	w.lock.Unlock()
+
+	event := &ApiChangeEvent{
+		Old: current.Apis,
+		New: fresh.Apis,
+		Config: fresh,
+	}
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (w *Watcher) fireError(file string, err error){
+	w.lock.RLock()
+	listeners := w.errListeners
+	w.lock.RUnlock()
+	if len(listeners)==0 {
+		color.Println("<red>reload config fail:</>", file, err)
+		return
+	}
+	for _, listener := range listeners {
+		listener(file, err)
+	}
+}
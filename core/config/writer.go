@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/paths"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+)
+
+// DefaultApplicationFile 新建应用配置文件的位置，环境变量中指定了配置文件时使用该文件，否则为用户配置目录下的application.yml
+func DefaultApplicationFile() string{
+	if file := os.Getenv(ApplicationEnvVar); file!="" {
+		return file
+	}
+	return filepath.Join(paths.ConfigDir(), "application.yml")
+}
+
+// SaveConfigContent 以yaml格式保存应用配置，未设置版本时使用当前版本，先写入临时文件再替换，避免写入一半的文件被加载
+// param
+//    path: 配置文件路径
+//    content: 配置内容
+func SaveConfigContent(path string, content *ConfigContent) error{
+	if content.Version==0 {
+		content.Version = ApplicationMigrations.Current()
+	}
+	data, err := yaml.Marshal(content)
+	if err!=nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err!=nil {
+		return err
+	}
+	if err = core.WriteFileAtomic(path, data, 0600); err!=nil {
+		return fmt.Errorf("写入配置文件失败: %s, %w", path, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 )
@@ -33,3 +34,26 @@ func CurrentPath() (string, error) {
 	}
 	return filepath.Dir(dir), nil
 }
+
+// WriteFileAtomic 先写入同目录下的临时文件再替换目标文件，避免其他程序读取到写入一半的文件
+//    path: 目标文件
+//    data: 文件内容
+//    perm: 文件权限
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error{
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "." + filepath.Base(path) + ".tmp")
+	if err!=nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err!=nil {
+		CloseQuietly(tmp)
+		return err
+	}
+	if err = tmp.Close(); err!=nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err!=nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
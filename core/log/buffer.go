@@ -0,0 +1,237 @@
+package log
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize 内存中保存的最近日志的条数
+const DefaultBufferSize = 1000
+
+// 订阅者的通道大小，订阅者处理不及时时丢弃新的日志，不阻塞写日志
+const subscriberSize = 100
+
+// Record 内存中保存的一条日志
+type Record struct {
+	// 序号，从1开始递增，可用于断线后继续获取
+	Seq uint64 						`json:"seq"`
+	Time time.Time 					`json:"time"`
+	Level string 					`json:"level"`
+	Module string 					`json:"module,omitempty"`
+	Message string 					`json:"message"`
+	Fields map[string]interface{} 	`json:"fields,omitempty"`
+}
+
+// Filter 日志的过滤条件，为空的条件不过滤
+type Filter struct {
+	// 最低级别
+	Level string
+	// 模块，包括子模块
+	Module string
+	// 消息或字段中包含的内容，不区分大小写
+	Text string
+	// 时间范围
+	Since time.Time
+	Until time.Time
+	// 只返回序号大于After的日志
+	After uint64
+	// 最多返回最近的条数，0表示不限制
+	Limit int
+}
+
+// Validate 校验过滤条件
+func (f *Filter) Validate() error{
+	if f.Level!="" {
+		if _, err := logrus.ParseLevel(f.Level); err!=nil {
+			return fmt.Errorf("日志级别无法识别: %s", f.Level)
+		}
+	}
+	if f.Limit<0 {
+		return fmt.Errorf("条数不能小于0: %d", f.Limit)
+	}
+	return nil
+}
+
+// Match 日志是否满足过滤条件
+func (f *Filter) Match(r *Record) bool{
+	if r.Seq<=f.After {
+		return false
+	}
+	if f.Level!="" {
+		min, err := logrus.ParseLevel(f.Level)
+		lv, _ := logrus.ParseLevel(r.Level)
+		if err==nil && lv>min {
+			return false
+		}
+	}
+	if f.Module!="" && r.Module!=f.Module && !strings.HasPrefix(r.Module, f.Module + ".") {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	if f.Text!="" {
+		text := strings.ToLower(f.Text)
+		if !strings.Contains(strings.ToLower(r.Message), text) && !strings.Contains(strings.ToLower(fmt.Sprint(r.Fields)), text) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRingBuffer 创建保存最近size条日志的hook
+func NewRingBuffer(size int) *RingBuffer{
+	if size<=0 {
+		size = DefaultBufferSize
+	}
+	return &RingBuffer{
+		records: make([]Record, 0, size),
+		size: size,
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// RingBuffer 在内存中保存最近的日志，超出条数时覆盖最早的日志，新的日志会推送给订阅者
+type RingBuffer struct {
+	lock sync.RWMutex
+	records []Record
+	size int
+	//下一条日志在records中的位置
+	next int
+	seq uint64
+	subscribers map[*Subscription]bool
+}
+
+func (b *RingBuffer) Levels() []logrus.Level{
+	return logrus.AllLevels
+}
+
+func (b *RingBuffer) Fire(entry *logrus.Entry) error{
+	record := Record{
+		Time: entry.Time,
+		Level: entry.Level.String(),
+		Message: entry.Message,
+	}
+	if len(entry.Data)>0 {
+		record.Fields = make(map[string]interface{}, len(entry.Data))
+		for k, v := range entry.Data {
+			if k=="module" {
+				record.Module = fmt.Sprint(v)
+				continue
+			}
+			record.Fields[k] = fieldValue(v)
+		}
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.seq++
+	record.Seq = b.seq
+	if len(b.records)<b.size {
+		b.records = append(b.records, record)
+	}else{
+		b.records[b.next] = record
+	}
+	b.next = (b.next+1) % b.size
+	for sub := range b.subscribers {
+		if sub.filter.Match(&record) {
+			select {
+			case sub.c <- record:
+			default:
+				sub.dropped++
+			}
+		}
+	}
+	return nil
+}
+
+// fieldValue 字段的值，error等无法直接转换为json的值转换为字符串
+func fieldValue(v interface{}) interface{}{
+	switch value := v.(type) {
+	case error:
+		return value.Error()
+	case fmt.Stringer:
+		return value.String()
+	}
+	return v
+}
+
+// Records 满足过滤条件的日志，按时间从早到晚排列
+func (b *RingBuffer) Records(filter Filter) []Record{
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	records := make([]Record, 0)
+	start := 0
+	if len(b.records)==b.size {
+		start = b.next
+	}
+	for i := 0; i<len(b.records); i++ {
+		record := b.records[(start+i) % len(b.records)]
+		if filter.Match(&record) {
+			records = append(records, record)
+		}
+	}
+	if filter.Limit>0 && len(records)>filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records
+}
+
+// Subscribe 订阅满足过滤条件的新日志，使用完后需要调用Close
+func (b *RingBuffer) Subscribe(filter Filter) *Subscription{
+	sub := &Subscription{buffer: b, filter: filter, c: make(chan Record, subscriberSize)}
+	sub.C = sub.c
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers[sub] = true
+	return sub
+}
+
+// Subscription 日志的订阅
+type Subscription struct {
+	// 新的日志，Close后关闭
+	C <-chan Record
+	c chan Record
+	buffer *RingBuffer
+	filter Filter
+	//通道已满时丢弃的日志条数
+	dropped int
+	closed bool
+}
+
+// Dropped 订阅者处理不及时而丢弃的日志条数
+func (s *Subscription) Dropped() int{
+	s.buffer.lock.RLock()
+	defer s.buffer.lock.RUnlock()
+	return s.dropped
+}
+
+// Close 取消订阅
+func (s *Subscription) Close(){
+	s.buffer.lock.Lock()
+	defer s.buffer.lock.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.buffer.subscribers, s)
+	close(s.c)
+}
+
+// buffer 全局日志与所有模块日志最近的日志
+var buffer = NewRingBuffer(DefaultBufferSize)
+
+// Recent 最近的日志中满足过滤条件的日志，按时间从早到晚排列
+func Recent(filter Filter) []Record{
+	return buffer.Records(filter)
+}
+
+// Subscribe 订阅满足过滤条件的新日志，使用完后需要调用Close
+func Subscribe(filter Filter) *Subscription{
+	return buffer.Subscribe(filter)
+}
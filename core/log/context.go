@@ -0,0 +1,37 @@
+package log
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// Fields 日志中附带的字段，如请求id、api的id、车次
+type Fields = logrus.Fields
+
+// Entry 附带字段的日志，通过 With 或 FromContext 获取，可继续调用 WithField、WithFields 添加字段
+type Entry = logrus.Entry
+
+type contextKey struct{}
+
+// With 创建附带字段的日志，字段会同时输出到控制台与json格式的日志文件中
+//
+// 示例：
+//	log.With(log.Fields{"api": "query_ticket", "train": "G1"}).Infof("查询余票: %d", count)
+func With(fields Fields) *Entry{
+	return log.WithFields(fields)
+}
+
+// NewContext 将日志保存到ctx中，之后通过 FromContext 获取
+func NewContext(ctx context.Context, entry *Entry) context.Context{
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// FromContext 获取ctx中保存的日志，ctx中没有日志时返回不带字段的日志
+func FromContext(ctx context.Context) *Entry{
+	if ctx!=nil {
+		if entry, ok := ctx.Value(contextKey{}).(*Entry); ok {
+			return entry
+		}
+	}
+	return logrus.NewEntry(log)
+}
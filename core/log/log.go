@@ -4,17 +4,44 @@ import (
 	"fmt"
 	"github.com/abeir/desktop-app/core"
 	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/paths"
 	"github.com/gookit/color"
-	"github.com/lestrrat-go/file-rotatelogs"
-	"github.com/pkg/errors"
-	"github.com/rifflock/lfshook"
 	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
 	"os"
-	"path"
-	"time"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
-var log *logrus.Logger
+// log 初始化之前输出到控制台，级别为info，InitLog在此基础上添加日志输出
+var log = newLogger()
+
+// outputLock 保护全局日志的输出、格式与hook，replaceHooks修改时持有写锁，模块日志通过 sharedOutput 读取
+var outputLock sync.RWMutex
+
+// redactor 遮盖敏感信息，是第一个hook，其他hook与所有输出只能看到遮盖后的内容
+var redactor = NewRedactor()
+
+// newLogger 创建全局日志，日志先遮盖敏感信息，再保存到buffer中
+func newLogger() *logrus.Logger{
+	logger := logrus.New()
+	logger.AddHook(redactor)
+	logger.AddHook(buffer)
+	return logger
+}
+
+// Redact 遮盖字符串中的敏感信息，用于需要在日志之外展示的内容
+func Redact(s string) string{
+	return redactor.Redact(s)
+}
+
+var (
+	initLock sync.Mutex
+	//InitLog创建的日志输出
+	current = &sinks{}
+)
 
 func IsTraceEnabled() bool{
 	return log.GetLevel() <= logrus.TraceLevel
@@ -140,82 +167,127 @@ func Panicln(args ...interface{}) {
 	log.Panicln(args...)
 }
 
-// InitLog 初始化日志，初始化失败时会抛出异常
+// SetLevel 修改全局日志级别，可在运行时调用，未单独设置级别的模块随之变化
+// param
+//    level: 日志级别，如 debug、info
+func SetLevel(level string) error{
+	return UpdateLevels(level, nil)
+}
+
+// InitLog 初始化日志，按 logger.sinks 输出到控制台与文件，文件位于配置的目录，目录无法写入时依次使用 paths.LogDir() 与临时目录
+// 初始化之前与初始化失败时日志只输出到控制台，再次调用时使用新的配置替换原有的输出，其他hook保持不变
 // param
 //    config: 系统配置
-func InitLog(config *config.ApplicationConfig){
-	if log!=nil {
-		log.Warn("日志系统已初始化，请不要重复调用InitLog")
-		return
+// return
+//    error: 日志配置有误或所有目录都无法写入，配置有误时不做任何修改
+func InitLog(config *config.ApplicationConfig) error{
+	initLock.Lock()
+	defer initLock.Unlock()
+	if _, err := logrus.ParseLevel(config.Logger.Level); err!=nil {
+		return fmt.Errorf("日志级别无法识别: %s, %w", config.Logger.Level, err)
 	}
-	var maxAgeDuration time.Duration
-	var rotationTimeDuration time.Duration
-	maxAge := config.Logger.MaxAge
-	if maxAge=="" {
-		maxAgeDuration = time.Hour * 24 * 60		//60天
-	}else{
-		duration, err := time.ParseDuration(maxAge)
-		if err!=nil {
-			panic(fmt.Sprintf("init log maxAge fail: %s, %s", maxAge, err.Error()))
-		}
-		maxAgeDuration = duration
+	levels, err := config.Logger.ModuleLevels()
+	if err==nil {
+		_, err = parseLevels(levels)
 	}
-	rotationTime := config.Logger.RotationTime
-	if rotationTime=="" {
-		rotationTimeDuration = time.Hour * 24	//1天
-	}else{
-		duration, err := time.ParseDuration(rotationTime)
-		if err!=nil {
-			panic(fmt.Sprintf("init log rotationTime fail: %s, %s", rotationTime, err.Error()))
-		}
-		rotationTimeDuration = duration
+	if err!=nil {
+		return fmt.Errorf("模块日志级别有误: %s, %w", config.Logger.Modules, err)
+	}
+	if _, err = CompileRedactPatterns(config.Logger.Redact.Patterns); err!=nil {
+		return fmt.Errorf("logger.redact.patterns: %w", err)
+	}
+	logPath, err := writableDir(config.Logger.Path)
+	if err!=nil {
+		return err
+	}
+	created, err := newSinks(config.Logger, logPath)
+	if err!=nil {
+		return err
+	}
+	color.Printf("<light_green>ready to init log:</> level:%s, logPath:%s, sinks:%d \n",
+		config.Logger.Level, logPath, len(created.hooks))
+	if err = redactor.Set(config.Logger.Redact.Fields, config.Logger.Redact.Patterns); err!=nil {
+		return err
 	}
-	configLocalFilesystemLogger(config.Logger.Level,
-		config.Logger.Path,
-		config.Logger.Filename,
-		maxAgeDuration,
-		rotationTimeDuration)
-}
-
-// config logrus log to local filesystem, with file rotation
-func configLocalFilesystemLogger(level string, logPath string, logFileName string,
-	maxAge time.Duration, rotationTime time.Duration) {
-	color.Printf("<light_green>ready to init log:</> level:%s, logPath:%s, logFilename:%s, maxAge:%s, rotationTime:%s \n",
-		level, logPath, logFileName, maxAge.String(), rotationTime.String())
-
-	if !core.IsExists(logPath) {
-		err := os.MkdirAll(logPath, os.ModePerm)
-		if err!=nil {
-			panic(err)
+	if err = SetModuleLevels(levels); err!=nil {
+		return err
+	}
+	if err = UpdateLevels(config.Logger.Level, nil); err!=nil {
+		return err
+	}
+	old := current
+	current = created
+	replaceHooks(old.hooks, created.hooks)
+	old.close()
+	return nil
+}
+
+// writableDir 依次尝试配置的日志目录、paths.LogDir() 与临时目录，返回第一个可以写入的目录
+// 未使用配置的目录时在控制台提示实际使用的目录
+func writableDir(logPath string) (string, error){
+	candidates := []string{logPath, paths.LogDir(), filepath.Join(os.TempDir(), paths.AppName, "log")}
+	var failures []string
+	for _, dir := range candidates {
+		if dir=="" {
+			continue
 		}
+		err := checkWritable(dir)
+		if err==nil {
+			if len(failures)>0 {
+				color.Printf("<yellow>log path is not writable, fallback to:</> %s\n", dir)
+				for _, failure := range failures {
+					color.Printf("<yellow>  </>%s\n", failure)
+				}
+			}
+			return dir, nil
+		}
+		failures = append(failures, err.Error())
 	}
-	log = logrus.New()
+	return "", fmt.Errorf("没有可以写入日志的目录: %s", strings.Join(failures, "; "))
+}
 
-	lv, err := logrus.ParseLevel(level)
+// checkWritable 创建目录并尝试写入文件
+func checkWritable(dir string) error{
+	if !core.IsExists(dir) {
+		if err := os.MkdirAll(dir, os.ModePerm); err!=nil {
+			return err
+		}
+	}
+	f, err := ioutil.TempFile(dir, ".writable")
 	if err!=nil {
-		log.Panicf("cannot parse log level: %s, %+v", level, errors.WithStack(err))
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// replaceHooks 使用新的输出替换原有的输出，其他hook保持不变，日志只通过输出写入，不再直接写入控制台
+func replaceHooks(old, hooks []logrus.Hook){
+	outputLock.Lock()
+	removed := make(map[logrus.Hook]bool, len(old))
+	for _, hook := range old {
+		removed[hook] = true
+	}
+	levelHooks := make(logrus.LevelHooks)
+	for lv, items := range log.Hooks {
+		for _, item := range items {
+			if !removed[item] {
+				levelHooks[lv] = append(levelHooks[lv], item)
+			}
+		}
 	}
-	log.SetLevel(lv)
-
-	baseLogPath := path.Join(logPath, logFileName)
-	writer, err := rotatelogs.New(
-		baseLogPath+".%Y%m%d%H%M",
-		rotatelogs.WithLinkName(baseLogPath), // 生成软链，指向最新日志文件
-		rotatelogs.WithMaxAge(maxAge), // 文件最大保存时间
-		rotatelogs.WithRotationTime(rotationTime), // 日志切割时间间隔
-	)
-	if err != nil {
-		log.Panicf("config local file system logger error. %+v", errors.WithStack(err))
+	for _, hook := range hooks {
+		levelHooks.Add(hook)
 	}
+	log.ReplaceHooks(levelHooks)
+	log.SetOutput(ioutil.Discard)
+	outputLock.Unlock()
+	modules.sync()
+}
 
-	lfHook := lfshook.NewHook(lfshook.WriterMap{
-		logrus.TraceLevel: writer,
-		logrus.DebugLevel: writer, // 为不同级别设置不同的输出目的
-		logrus.InfoLevel:  writer,
-		logrus.WarnLevel:  writer,
-		logrus.ErrorLevel: writer,
-		logrus.FatalLevel: writer,
-		logrus.PanicLevel: writer,
-	}, &logrus.JSONFormatter{})
-	log.AddHook(lfHook)
+// sharedOutput 模块日志与全局日志共用的输出、hook与格式，hook只会整体替换，不会修改返回的map
+func sharedOutput() (io.Writer, logrus.LevelHooks, logrus.Formatter){
+	outputLock.RLock()
+	defer outputLock.RUnlock()
+	return log.Out, log.Hooks, log.Formatter
 }
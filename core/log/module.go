@@ -0,0 +1,186 @@
+package log
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+)
+
+// Levels 日志级别
+type Levels struct {
+	// 全局日志级别
+	Level string 	`json:"level"`
+	// 单独设置了级别的模块
+	Modules map[string]string 	`json:"modules"`
+	// 已创建的模块及其生效的级别
+	Effective map[string]string 	`json:"effective,omitempty"`
+}
+
+// moduleRegistry 模块日志，模块名以 . 分隔层级，如 service.station 未设置级别时使用 service 的级别
+type moduleRegistry struct {
+	lock sync.Mutex
+	loggers map[string]*logrus.Logger
+	//单独设置的级别
+	levels map[string]logrus.Level
+}
+
+var modules = &moduleRegistry{
+	loggers: make(map[string]*logrus.Logger),
+	levels: make(map[string]logrus.Level),
+}
+
+// Module 获取模块的日志，日志中附带 module 字段，各模块可以使用不同的日志级别
+// 模块日志与全局日志共用输出与hook
+//
+// 示例：
+//	log.Module("service.station").Debugf("车站数量: %d", len(stations))
+func Module(name string) *Entry{
+	modules.lock.Lock()
+	defer modules.lock.Unlock()
+	logger, ok := modules.loggers[name]
+	if !ok {
+		out, hooks, formatter := sharedOutput()
+		logger = &logrus.Logger{
+			Out: out,
+			Hooks: hooks,
+			Formatter: formatter,
+			ReportCaller: log.ReportCaller,
+			ExitFunc: log.ExitFunc,
+			Level: modules.levelOf(name),
+		}
+		modules.loggers[name] = logger
+	}
+	return logrus.NewEntry(logger).WithField("module", name)
+}
+
+// SetModuleLevel 修改模块的日志级别，可在运行时调用，子模块未单独设置时随之变化
+// param
+//    name: 模块名
+//    level: 日志级别，为空时取消单独设置的级别
+func SetModuleLevel(name, level string) error{
+	return UpdateLevels("", map[string]string{name: level})
+}
+
+// SetModuleLevels 替换所有模块单独设置的级别，未包含的模块使用全局级别
+func SetModuleLevels(levels map[string]string) error{
+	parsed, err := parseLevels(levels)
+	if err!=nil {
+		return err
+	}
+	modules.lock.Lock()
+	defer modules.lock.Unlock()
+	modules.levels = make(map[string]logrus.Level, len(parsed))
+	for name, lv := range parsed {
+		if lv!=nil {
+			modules.levels[name] = *lv
+		}
+	}
+	modules.apply()
+	return nil
+}
+
+// UpdateLevels 同时修改全局与模块的日志级别，任一级别无法识别时不做任何修改
+// param
+//    level: 全局日志级别，为空时不修改
+//    levels: 模块的日志级别，级别为空时取消单独设置的级别，未包含的模块不修改
+func UpdateLevels(level string, levels map[string]string) error{
+	var root logrus.Level
+	if level!="" {
+		lv, err := logrus.ParseLevel(level)
+		if err!=nil {
+			return err
+		}
+		root = lv
+	}
+	parsed, err := parseLevels(levels)
+	if err!=nil {
+		return err
+	}
+	modules.lock.Lock()
+	defer modules.lock.Unlock()
+	if level!="" {
+		log.SetLevel(root)
+	}
+	for name, lv := range parsed {
+		if lv==nil {
+			delete(modules.levels, name)
+		}else{
+			modules.levels[name] = *lv
+		}
+	}
+	modules.apply()
+	return nil
+}
+
+// GetLevels 当前的全局与模块的日志级别
+func GetLevels() Levels{
+	modules.lock.Lock()
+	defer modules.lock.Unlock()
+	levels := Levels{
+		Level: log.GetLevel().String(),
+		Modules: make(map[string]string, len(modules.levels)),
+		Effective: make(map[string]string, len(modules.loggers)),
+	}
+	for name, lv := range modules.levels {
+		levels.Modules[name] = lv.String()
+	}
+	for name, logger := range modules.loggers {
+		levels.Effective[name] = logger.GetLevel().String()
+	}
+	return levels
+}
+
+// parseLevels 解析模块的日志级别，级别为空时对应nil
+func parseLevels(levels map[string]string) (map[string]*logrus.Level, error){
+	parsed := make(map[string]*logrus.Level, len(levels))
+	for name, level := range levels {
+		if strings.TrimSpace(name)=="" {
+			return nil, fmt.Errorf("模块名不能为空")
+		}
+		if level=="" {
+			parsed[name] = nil
+			continue
+		}
+		lv, err := logrus.ParseLevel(level)
+		if err!=nil {
+			return nil, fmt.Errorf("模块 %s 的日志级别无法识别: %w", name, err)
+		}
+		parsed[name] = &lv
+	}
+	return parsed, nil
+}
+
+// levelOf 模块生效的级别，依次查找模块与上级模块单独设置的级别，都未设置时使用全局级别
+func (m *moduleRegistry) levelOf(name string) logrus.Level{
+	for {
+		if lv, ok := m.levels[name]; ok {
+			return lv
+		}
+		idx := strings.LastIndex(name, ".")
+		if idx<0 {
+			return log.GetLevel()
+		}
+		name = name[:idx]
+	}
+}
+
+// apply 重新计算所有模块的级别，只修改级别，输出与hook保持不变
+func (m *moduleRegistry) apply(){
+	for name, logger := range m.loggers {
+		logger.SetLevel(m.levelOf(name))
+	}
+}
+
+// sync 全局日志重新配置后，使模块日志使用新的输出与hook
+func (m *moduleRegistry) sync(){
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	out, hooks, formatter := sharedOutput()
+	for name, logger := range m.loggers {
+		logger.SetOutput(out)
+		logger.SetFormatter(formatter)
+		logger.ReplaceHooks(hooks)
+		logger.SetLevel(m.levelOf(name))
+	}
+}
@@ -0,0 +1,188 @@
+package log
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask 遮盖敏感信息后的内容
+const Mask = "******"
+
+// BuiltinRedactFields 内置的敏感字段名，不区分大小写，与 config show 使用同一份名单
+var BuiltinRedactFields = core.SecretNames
+
+// BuiltinRedactPatterns 内置的敏感内容，存在分组时只遮盖第一个分组
+var BuiltinRedactPatterns = []string{
+	//认证相关的请求头与响应头
+	`(?i)\b(?:authorization|proxy-authorization|cookie|set-cookie)\s*[:=]\s*([^\r\n]+)`,
+	//cookie、查询字符串与表单中的会话与密码
+	`(?i)\b` + core.SecretNamePattern() + `=([^;&\s"]+)`,
+	//身份证号
+	`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`,
+	//手机号
+	`\b1[3-9]\d{9}\b`,
+}
+
+// NewRedactor 创建遮盖敏感信息的hook，包含内置的字段与正则表达式
+func NewRedactor() *Redactor{
+	r := &Redactor{}
+	if err := r.Set(nil, nil); err!=nil {
+		panic(err)
+	}
+	return r
+}
+
+// Redactor 在日志写入任何输出之前遮盖消息与字段中的敏感信息，需要作为第一个hook
+// 敏感字段的值整体遮盖，其他字符串按正则表达式遮盖匹配的内容
+type Redactor struct {
+	lock sync.RWMutex
+	fields map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// CompileRedactPatterns 编译正则表达式，用于校验配置
+func CompileRedactPatterns(patterns []string) ([]*regexp.Regexp, error){
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err!=nil {
+			return nil, fmt.Errorf("无法解析的正则表达式: %s, %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Set 设置内置之外的字段名与正则表达式，替换之前设置的内容
+//    fields: 字段名，不区分大小写，json内容中同名字段的值同样会被遮盖
+//    patterns: 正则表达式，存在分组时只遮盖第一个分组
+func (r *Redactor) Set(fields []string, patterns []string) error{
+	compiled, err := CompileRedactPatterns(append(append([]string{}, BuiltinRedactPatterns...), patterns...))
+	if err!=nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, field := range append(append([]string{}, BuiltinRedactFields...), fields...) {
+		names[strings.ToLower(strings.TrimSpace(field))] = true
+	}
+	compiled = append([]*regexp.Regexp{jsonFieldPattern(names)}, compiled...)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.fields = names
+	r.patterns = compiled
+	return nil
+}
+
+// jsonFieldPattern 匹配json中敏感字段的字符串值，如请求日志中记录的请求体 {"password":"..."}
+func jsonFieldPattern(names map[string]bool) *regexp.Regexp{
+	quoted := make([]string, 0, len(names))
+	for name := range names {
+		if name!="" {
+			quoted = append(quoted, regexp.QuoteMeta(name))
+		}
+	}
+	sort.Strings(quoted)
+	return regexp.MustCompile(`(?i)"(?:` + strings.Join(quoted, "|") + `)"\s*:\s*"((?:[^"\\]|\\.)*)"`)
+}
+
+func (r *Redactor) Levels() []logrus.Level{
+	return logrus.AllLevels
+}
+
+// Fire 遮盖消息与字段，字段替换为新的map，不修改调用方的Entry中的字段
+func (r *Redactor) Fire(entry *logrus.Entry) error{
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	entry.Message = r.redactString(entry.Message)
+	if len(entry.Data)==0 {
+		return nil
+	}
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = r.redactField(k, v)
+	}
+	entry.Data = data
+	return nil
+}
+
+// Redact 遮盖字符串中的敏感内容
+func (r *Redactor) Redact(s string) string{
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.redactString(s)
+}
+
+func (r *Redactor) redactField(name string, value interface{}) interface{}{
+	if value==nil {
+		return nil
+	}
+	if r.fields[strings.ToLower(name)] || core.IsSecretName(name) {
+		return Mask
+	}
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case error:
+		if s := r.redactString(v.Error()); s!=v.Error() {
+			return s
+		}
+		return v
+	case fmt.Stringer:
+		if s := r.redactString(v.String()); s!=v.String() {
+			return s
+		}
+		return v
+	}
+	//请求头等以字符串为键的map，逐个遮盖
+	rv := reflect.ValueOf(value)
+	if rv.Kind()==reflect.Map && rv.Type().Key().Kind()==reflect.String {
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = r.redactField(iter.Key().String(), iter.Value().Interface())
+		}
+		return m
+	}
+	if rv.Kind()==reflect.Slice && rv.Type().Elem().Kind()==reflect.String {
+		s := make([]string, rv.Len())
+		for i := range s {
+			s[i] = r.redactString(rv.Index(i).String())
+		}
+		return s
+	}
+	return value
+}
+
+func (r *Redactor) redactString(s string) string{
+	for _, re := range r.patterns {
+		s = redactPattern(re, s)
+	}
+	return s
+}
+
+// redactPattern 遮盖匹配的内容，存在分组时只遮盖第一个分组
+func redactPattern(re *regexp.Regexp, s string) string{
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches)==0 {
+		return s
+	}
+	var result strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m)>=4 && m[2]>=0 {
+			start, end = m[2], m[3]
+		}
+		result.WriteString(s[last:start])
+		result.WriteString(Mask)
+		last = end
+	}
+	result.WriteString(s[last:])
+	return result.String()
+}
@@ -0,0 +1,271 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 切割后文件名中的时间格式
+const rotateTimeFormat = "20060102-150405.000"
+
+// RotateOptions 日志文件的切割方式
+type RotateOptions struct {
+	// 文件超过此大小时切割，0表示不按大小切割
+	MaxSize int64
+	// 切割的时间间隔，按本地时间对齐，如 24h 在每天0点切割，0表示不按时间切割
+	RotationTime time.Duration
+	// 切割后的文件最多保留的个数，0表示不限制
+	MaxBackups int
+	// 切割后的文件最长保留的时间，0表示不限制
+	MaxAge time.Duration
+	// 是否使用gzip压缩切割后的文件
+	Compress bool
+}
+
+// NewRotateWriter 创建写入日志文件的Writer，文件在第一次写入时打开
+//    filename: 日志文件，切割后的文件位于同一目录，如 error.log.20060102-150405.000，压缩后加上 .gz
+//    options: 切割方式
+func NewRotateWriter(filename string, options RotateOptions) *RotateWriter{
+	return &RotateWriter{filename: filename, options: options}
+}
+
+// RotateWriter 按大小与时间切割的日志文件，可在多个goroutine中使用
+// 压缩与清理切割后的文件在后台进行，Close时等待完成
+type RotateWriter struct {
+	lock sync.Mutex
+	filename string
+	options RotateOptions
+	file *os.File
+	//当前文件的大小
+	size int64
+	//当前文件需要按时间切割的时间
+	rotateAt time.Time
+	//压缩与清理切割后的文件
+	post sync.WaitGroup
+	postLock sync.Mutex
+}
+
+// Filename 日志文件
+func (w *RotateWriter) Filename() string{
+	return w.filename
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error){
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	now := time.Now()
+	if w.file==nil {
+		if err := w.open(now); err!=nil {
+			return 0, err
+		}
+	}
+	timeout := w.options.RotationTime>0 && !now.Before(w.rotateAt)
+	oversize := w.options.MaxSize>0 && w.size>0 && w.size+int64(len(p))>w.options.MaxSize
+	if timeout || oversize {
+		if err := w.rotate(now); err!=nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即切割日志文件
+func (w *RotateWriter) Rotate() error{
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	now := time.Now()
+	if w.file==nil {
+		if err := w.open(now); err!=nil {
+			return err
+		}
+	}
+	return w.rotate(now)
+}
+
+// Close 关闭日志文件，等待后台的压缩与清理完成
+func (w *RotateWriter) Close() error{
+	w.lock.Lock()
+	var err error
+	if w.file!=nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.lock.Unlock()
+	w.post.Wait()
+	return err
+}
+
+// open 打开已存在的日志文件继续写入，文件在上一个切割周期中修改过时先切割
+func (w *RotateWriter) open(now time.Time) error{
+	if err := os.MkdirAll(filepath.Dir(w.filename), os.ModePerm); err!=nil {
+		return err
+	}
+	if w.options.RotationTime>0 {
+		if info, err := os.Stat(w.filename); err==nil && info.Size()>0 && info.ModTime().Before(w.periodStart(now)) {
+			return w.rotate(now)
+		}
+	}
+	return w.openFile(now)
+}
+
+func (w *RotateWriter) openFile(now time.Time) error{
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err!=nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err!=nil {
+		core.CloseQuietly(file)
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.rotateAt = w.periodStart(now).Add(w.options.RotationTime)
+	return nil
+}
+
+// periodStart 当前切割周期的开始时间，按本地时间对齐
+func (w *RotateWriter) periodStart(now time.Time) time.Time{
+	if w.options.RotationTime<=0 {
+		return now
+	}
+	_, offset := now.Zone()
+	zone := time.Duration(offset) * time.Second
+	return now.Add(zone).Truncate(w.options.RotationTime).Add(-zone)
+}
+
+// rotate 将当前文件重命名为带时间的文件并打开新文件
+func (w *RotateWriter) rotate(now time.Time) error{
+	if w.file!=nil {
+		if err := w.file.Close(); err!=nil {
+			return err
+		}
+		w.file = nil
+	}
+	if info, err := os.Stat(w.filename); err==nil && info.Size()>0 {
+		backup := w.backupName(now)
+		if err = os.Rename(w.filename, backup); err!=nil {
+			return fmt.Errorf("切割日志文件失败: %w", err)
+		}
+		w.post.Add(1)
+		go w.afterRotate(backup)
+	}
+	return w.openFile(now)
+}
+
+// backupName 切割后的文件名，同一时间切割多次时加上序号
+func (w *RotateWriter) backupName(now time.Time) string{
+	name := w.filename + "." + now.Format(rotateTimeFormat)
+	backup := name
+	for i := 1; core.IsExists(backup) || core.IsExists(backup + ".gz"); i++ {
+		backup = fmt.Sprintf("%s-%d", name, i)
+	}
+	return backup
+}
+
+// afterRotate 压缩切割后的文件并清理超出数量或时间的文件
+func (w *RotateWriter) afterRotate(backup string){
+	defer w.post.Done()
+	w.postLock.Lock()
+	defer w.postLock.Unlock()
+	if w.options.Compress {
+		if err := compressFile(backup); err!=nil {
+			fmt.Fprintf(os.Stderr, "压缩日志文件失败: %s, %s\n", backup, err)
+		}
+	}
+	if err := w.cleanup(); err!=nil {
+		fmt.Fprintf(os.Stderr, "清理日志文件失败: %s, %s\n", w.filename, err)
+	}
+}
+
+// backups 切割后的文件，按时间从新到旧排列
+func (w *RotateWriter) backups() ([]os.FileInfo, error){
+	infos, err := ioutil.ReadDir(filepath.Dir(w.filename))
+	if err!=nil {
+		return nil, err
+	}
+	prefix := filepath.Base(w.filename) + "."
+	var backups []os.FileInfo
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		//只处理以时间结尾的文件，避免误删以相同前缀命名的其他日志文件
+		if rest := name[len(prefix):]; rest=="" || rest[0]<'0' || rest[0]>'9' {
+			continue
+		}
+		backups = append(backups, info)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Name() > backups[j].Name()
+	})
+	return backups, nil
+}
+
+func (w *RotateWriter) cleanup() error{
+	if w.options.MaxBackups<=0 && w.options.MaxAge<=0 {
+		return nil
+	}
+	backups, err := w.backups()
+	if err!=nil {
+		return err
+	}
+	dir := filepath.Dir(w.filename)
+	deadline := time.Now().Add(-w.options.MaxAge)
+	for i, info := range backups {
+		expired := w.options.MaxAge>0 && info.ModTime().Before(deadline)
+		if (w.options.MaxBackups>0 && i>=w.options.MaxBackups) || expired {
+			if err = os.Remove(filepath.Join(dir, info.Name())); err!=nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressFile 使用gzip压缩文件，压缩后删除原文件
+func compressFile(file string) error{
+	src, err := os.Open(file)
+	if err!=nil {
+		return err
+	}
+	defer core.CloseQuietly(src)
+	info, err := src.Stat()
+	if err!=nil {
+		return err
+	}
+	dst, err := os.OpenFile(file + ".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err!=nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	gz.Name = filepath.Base(file)
+	gz.ModTime = info.ModTime()
+	_, err = io.Copy(gz, src)
+	if err==nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err==nil {
+		err = closeErr
+	}
+	if err!=nil {
+		_ = os.Remove(file + ".gz")
+		return err
+	}
+	core.CloseQuietly(src)
+	//保留原文件的修改时间，用于按时间清理
+	_ = os.Chtimes(file + ".gz", info.ModTime(), info.ModTime())
+	return os.Remove(file)
+}
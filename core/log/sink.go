@@ -0,0 +1,164 @@
+package log
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 日志中时间的格式
+const timestampFormat = "2006-01-02 15:04:05.000"
+
+// consoleOut 控制台输出
+var consoleOut io.Writer = os.Stderr
+
+// sinkHook 将日志按指定的格式写入一个输出，低于最低级别的日志不输出
+type sinkHook struct {
+	lock sync.Mutex
+	levels []logrus.Level
+	formatter logrus.Formatter
+	writer io.Writer
+}
+
+func (h *sinkHook) Levels() []logrus.Level{
+	return h.levels
+}
+
+func (h *sinkHook) Fire(entry *logrus.Entry) error{
+	data, err := h.formatter.Format(entry)
+	if err!=nil {
+		return err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, err = h.writer.Write(data)
+	return err
+}
+
+// sinks InitLog创建的所有输出
+type sinks struct {
+	hooks []logrus.Hook
+	closers []io.Closer
+}
+
+func (s *sinks) close(){
+	for _, closer := range s.closers {
+		core.CloseQuietly(closer)
+	}
+}
+
+// newSinks 根据配置创建日志输出，任一输出创建失败时关闭已创建的输出
+//    logPath: 日志目录，文件输出的相对路径相对于此目录
+func newSinks(logger config.Logger, logPath string) (*sinks, error){
+	s := &sinks{}
+	for i, sink := range logger.EffectiveSinks() {
+		hook, closer, err := newSink(sink, logPath)
+		if err!=nil {
+			s.close()
+			return nil, fmt.Errorf("logger.sinks[%d]: %w", i, err)
+		}
+		s.hooks = append(s.hooks, hook)
+		if closer!=nil {
+			s.closers = append(s.closers, closer)
+		}
+	}
+	return s, nil
+}
+
+func newSink(sink config.LogSink, logPath string) (logrus.Hook, io.Closer, error){
+	levels := logrus.AllLevels
+	if sink.Level!="" {
+		lv, err := logrus.ParseLevel(sink.Level)
+		if err!=nil {
+			return nil, nil, err
+		}
+		levels = levelsFrom(lv)
+	}
+	formatter, err := newFormatter(sink)
+	if err!=nil {
+		return nil, nil, err
+	}
+	switch sink.Type {
+	case config.SinkConsole:
+		return &sinkHook{levels: levels, formatter: formatter, writer: consoleOut}, nil, nil
+	case config.SinkFile:
+		options, err := rotateOptions(sink)
+		if err!=nil {
+			return nil, nil, err
+		}
+		filename := sink.Filename
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(logPath, filename)
+		}
+		writer := NewRotateWriter(filename, options)
+		return &sinkHook{levels: levels, formatter: formatter, writer: writer}, writer, nil
+	}
+	return nil, nil, fmt.Errorf("不支持的日志输出: %s", sink.Type)
+}
+
+// levelsFrom 不低于lv的所有级别
+func levelsFrom(lv logrus.Level) []logrus.Level{
+	var levels []logrus.Level
+	for _, l := range logrus.AllLevels {
+		if l<=lv {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func newFormatter(sink config.LogSink) (logrus.Formatter, error){
+	switch sink.FormatOrDefault() {
+	case config.FormatText:
+		return &logrus.TextFormatter{
+			ForceColors: sink.Color,
+			DisableColors: !sink.Color,
+			FullTimestamp: true,
+			TimestampFormat: timestampFormat,
+		}, nil
+	case config.FormatLogfmt:
+		return &logrus.TextFormatter{
+			DisableColors: true,
+			FullTimestamp: true,
+			TimestampFormat: time.RFC3339Nano,
+		}, nil
+	case config.FormatJson:
+		return &logrus.JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("不支持的日志格式: %s", sink.Format)
+}
+
+func rotateOptions(sink config.LogSink) (RotateOptions, error){
+	options := RotateOptions{MaxBackups: sink.MaxBackups, Compress: sink.Compress}
+	var err error
+	if sink.MaxSize!="" {
+		if options.MaxSize, err = core.ParseSize(sink.MaxSize); err!=nil {
+			return options, err
+		}
+	}
+	if options.RotationTime, err = parseDuration("rotationTime", sink.RotationTime); err!=nil {
+		return options, err
+	}
+	if options.MaxAge, err = parseDuration("maxAge", sink.MaxAge); err!=nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// parseDuration 解析日志配置中的时间，未配置时为0
+func parseDuration(name, value string) (time.Duration, error){
+	if value=="" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err!=nil {
+		return 0, fmt.Errorf("日志配置 %s 格式错误: %s, %w", name, value, err)
+	}
+	return duration, nil
+}
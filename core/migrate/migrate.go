@@ -0,0 +1,186 @@
+// Package migrate 配置文件的版本与升级，旧版本的文档按版本号逐步执行升级函数，直到当前版本
+//
+// 文档中使用 version 字段记录版本，没有该字段时视为 DefaultVersion，
+// 每个升级函数将文档从 From 版本升级到 From+1 版本，如：
+//	registry := migrate.NewRegistry("application")
+//	registry.Register(1, "logger.maxAge 改为小时数", func(doc map[string]interface{}) error { ... })
+package migrate
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VersionKey 文档中记录版本的字段
+const VersionKey = "version"
+
+// DefaultVersion 没有version字段的文档的版本
+const DefaultVersion = 1
+
+// Func 升级函数，直接修改doc
+type Func func(doc map[string]interface{}) error
+
+// Migration 将文档从 From 版本升级到 From+1 版本
+type Migration struct {
+	From int
+	// 升级内容的说明，会记录在日志中
+	Description string
+	Migrate Func
+}
+
+func (m Migration) String() string{
+	return fmt.Sprintf("v%d -> v%d: %s", m.From, m.From+1, m.Description)
+}
+
+// NewRegistry 创建升级函数的注册表
+//    name: 文档的名称，用于错误信息，如 application
+func NewRegistry(name string) *Registry{
+	return &Registry{name: name, migrations: make(map[int]Migration)}
+}
+
+// Registry 一类文档的升级函数
+type Registry struct {
+	lock sync.RWMutex
+	name string
+	migrations map[int]Migration
+}
+
+// Register 注册升级函数，同一版本重复注册时panic，通常在init函数中调用
+//    from: 升级前的版本
+//    description: 升级内容的说明
+//    fn: 升级函数
+func (r *Registry) Register(from int, description string, fn Func) *Registry{
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.migrations[from]; ok {
+		panic(fmt.Sprintf("%s 已注册了从 v%d 升级的函数", r.name, from))
+	}
+	r.migrations[from] = Migration{From: from, Description: description, Migrate: fn}
+	return r
+}
+
+// Current 当前版本，即从 DefaultVersion 开始连续注册的升级函数能升级到的版本
+func (r *Registry) Current() int{
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	version := DefaultVersion
+	for {
+		if _, ok := r.migrations[version]; !ok {
+			return version
+		}
+		version++
+	}
+}
+
+// Migrate 将文档升级到当前版本，升级后doc中的version为当前版本
+// return
+//    []Migration: 按执行顺序排列的升级函数，已是当前版本时为空
+//    error: 文档版本高于当前版本或升级失败，升级失败时doc可能已被部分修改
+func (r *Registry) Migrate(doc map[string]interface{}) ([]Migration, error){
+	version, err := Version(doc)
+	if err!=nil {
+		return nil, err
+	}
+	current := r.Current()
+	if version > current {
+		return nil, fmt.Errorf("%s 的版本为 v%d，高于程序支持的版本 v%d，请升级程序", r.name, version, current)
+	}
+	r.lock.RLock()
+	steps := make([]Migration, 0, current-version)
+	for v := version; v < current; v++ {
+		steps = append(steps, r.migrations[v])
+	}
+	r.lock.RUnlock()
+
+	for _, m := range steps {
+		if err = m.Migrate(doc); err!=nil {
+			return nil, fmt.Errorf("%s 升级失败 %s: %w", r.name, m, err)
+		}
+		doc[VersionKey] = m.From + 1
+	}
+	return steps, nil
+}
+
+// Version 获取文档的版本，没有version字段时为 DefaultVersion
+func Version(doc map[string]interface{}) (int, error){
+	value, ok := doc[VersionKey]
+	if !ok || value==nil {
+		return DefaultVersion, nil
+	}
+	var version int
+	switch v := value.(type) {
+	case int:
+		version = v
+	case int64:
+		version = int(v)
+	case float64:
+		version = int(v)
+		if float64(version)!=v {
+			return 0, fmt.Errorf("version 必须为整数: %v", v)
+		}
+	case string:
+		i, err := strconv.Atoi(v)
+		if err!=nil {
+			return 0, fmt.Errorf("version 必须为整数: %s", v)
+		}
+		version = i
+	default:
+		return 0, fmt.Errorf("version 必须为整数: %v", v)
+	}
+	if version < DefaultVersion {
+		return 0, fmt.Errorf("version 不能小于 %d: %d", DefaultVersion, version)
+	}
+	return version, nil
+}
+
+// Backup 在升级前备份文件，备份文件与原文件位于同一目录，如 application.yml.v1.bak，已存在时加上时间
+//    file: 原文件
+//    version: 原文件的版本
+// return
+//    string: 备份文件
+func Backup(file string, version int) (string, error){
+	data, err := ioutil.ReadFile(file)
+	if err!=nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", file, version)
+	if core.IsExists(backup) {
+		backup = fmt.Sprintf("%s.v%d.%s.bak", file, version, time.Now().Format("20060102150405"))
+	}
+	info, err := os.Stat(file)
+	if err!=nil {
+		return "", err
+	}
+	if err = ioutil.WriteFile(backup, data, info.Mode().Perm()); err!=nil {
+		return "", fmt.Errorf("备份文件失败: %w", err)
+	}
+	return backup, nil
+}
+
+// Normalize 将yaml解析出的 map[interface{}]interface{} 转换为 map[string]interface{}，以便升级函数统一处理
+func Normalize(value interface{}) interface{}{
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = Normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = Normalize(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = Normalize(item)
+		}
+		return v
+	}
+	return value
+}
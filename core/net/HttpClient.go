@@ -1,8 +1,8 @@
 package net
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"github.com/abeir/desktop-app/core"
 	"github.com/abeir/desktop-app/core/log"
 	"io"
@@ -95,6 +95,9 @@ type HttpClient struct {
 	cookies []http.Cookie
 	body io.Reader
 	rspHeaders map[string][]string
+	statusCode int
+	retryPolicy *RetryPolicy
+	requestTimeout time.Duration
 	err error
 }
 
@@ -135,6 +138,47 @@ func (h *HttpClient) SetMethod(method HttpMethod) *HttpClient{
 	return h
 }
 
+// SetTimeout 设置每次请求的超时时间，只对当前实例生效，0表示不限制，设置了重试策略时每次重试分别计算
+func (h *HttpClient) SetTimeout(timeout time.Duration) *HttpClient{
+	c := *h.client
+	c.Timeout = timeout
+	h.client = &c
+	return h
+}
+
+// SetRequestTimeout 设置整个请求的超时时间，包括重试与重试前的等待，只对当前实例生效，0表示不限制
+func (h *HttpClient) SetRequestTimeout(timeout time.Duration) *HttpClient{
+	h.requestTimeout = timeout
+	return h
+}
+
+// SetCookieJar 设置保存与发送cookie的CookieJar，只对当前实例生效，响应中的Set-Cookie会保存到jar中
+func (h *HttpClient) SetCookieJar(jar http.CookieJar) *HttpClient{
+	c := *h.client
+	c.Jar = jar
+	h.client = &c
+	return h
+}
+
+// UseAccount 使用账号的cookie，cookie保存在数据目录中，重启后继续使用
+//    account: 账号，为空时使用 DefaultAccount
+func (h *HttpClient) UseAccount(account string) *HttpClient{
+	jar, err := AccountJar(account)
+	if err!=nil {
+		h.err = err
+		log.Module("net").Error(err)
+		return h
+	}
+	return h.SetCookieJar(jar)
+}
+
+// SetRetryPolicy 设置请求失败时的重试策略，只对Request与RequestContext方法生效，为nil时不重试
+// 重试时会重新发送请求体，SetBodyStream设置的请求体会先读取到内存中
+func (h *HttpClient) SetRetryPolicy(policy *RetryPolicy) *HttpClient{
+	h.retryPolicy = policy
+	return h
+}
+
 // SetBody 设置请求体内容
 func (h *HttpClient) SetBody(body []byte) *HttpClient{
 	h.body = bytes.NewBuffer(body)
@@ -199,7 +243,7 @@ func (h *HttpClient) MultipartForm(params map[string][]string, files map[string]
 			if values==nil || len(values)==0 {
 				if err := bodyWriter.WriteField(name, ""); err!=nil {
 					h.err = err
-					log.Error(err)
+					log.Module("net").Error(err)
 					return h
 				}
 				continue
@@ -207,7 +251,7 @@ func (h *HttpClient) MultipartForm(params map[string][]string, files map[string]
 			for _, val := range values {
 				if err := bodyWriter.WriteField(name, val); err!=nil {
 					h.err = err
-					log.Error(err)
+					log.Module("net").Error(err)
 					return h
 				}
 			}
@@ -219,19 +263,19 @@ func (h *HttpClient) MultipartForm(params map[string][]string, files map[string]
 			fileWriter, err := bodyWriter.CreateFormFile(name, filename)
 			if err!=nil {
 				h.err = err
-				log.Error(err)
+				log.Module("net").Error(err)
 				return h
 			}
 			if err = h.copy(file, fileWriter); err!=nil {
 				h.err = err
-				log.Error(err)
+				log.Module("net").Error(err)
 				return h
 			}
 		}
 	}
 	if err := bodyWriter.Close(); err!=nil {
 		h.err = err
-		log.Error(err)
+		log.Module("net").Error(err)
 		return h
 	}
 	h.SetContentType(ContentType(bodyWriter.FormDataContentType()))
@@ -248,11 +292,11 @@ func (h *HttpClient) copy(srcFile string, dst io.Writer) error{
 	return nil
 }
 
-func (h *HttpClient) doRequest(url string) (rsp *http.Response, err error){
+func (h *HttpClient) doRequest(ctx context.Context, url string, body io.Reader) (rsp *http.Response, err error){
 	if h.err!=nil {
 		return nil, h.err
 	}
-	req, err := http.NewRequest(h.method.ToString(), url, h.body)
+	req, err := http.NewRequestWithContext(ctx, h.method.ToString(), url, body)
 	if err!=nil {
 		return nil, err
 	}
@@ -266,43 +310,118 @@ func (h *HttpClient) doRequest(url string) (rsp *http.Response, err error){
 }
 
 func (h *HttpClient) extractRspHeaders(rsp *http.Response){
+	h.statusCode = rsp.StatusCode
+	h.rspHeaders = make(map[string][]string, len(rsp.Header))
 	for k,v := range rsp.Header {
 		h.rspHeaders[k] = v
 	}
 }
 
 // Request 发送请求，调用成功后可使用 ResponseHeaders方法获取响应头
+// 设置了重试策略时，满足重试条件的请求会在等待后重新发送，最后一次请求的结果作为返回值
 //    url: 请求地址
 // return
 //    body: 响应内容
 //    err: 请求过程中出现的错误
 func (h *HttpClient) Request(url string) (body []byte, err error){
-	rsp, err := h.doRequest(url)
+	return h.RequestContext(context.Background(), url)
+}
+
+// RequestContext 与 Request 相同，ctx取消或超时后立即中断请求与重试前的等待，返回ctx的错误
+//    ctx: 请求的context，如处理页面请求时使用页面请求的context，页面请求中断后不再继续请求
+//    url: 请求地址
+// return
+//    body: 响应内容
+//    err: 请求过程中出现的错误
+func (h *HttpClient) RequestContext(ctx context.Context, url string) (body []byte, err error){
+	if h.err!=nil {
+		return nil, h.err
+	}
+	if h.requestTimeout>0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.requestTimeout)
+		defer cancel()
+	}
+	policy := h.retryPolicy
+	if policy==nil || !policy.Retryable(h.method.ToString()) {
+		return h.requestOnce(ctx, url, h.body)
+	}
+	nextBody, err := rewindBody(h.body)
+	if err!=nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		body, err = h.requestOnce(ctx, url, nextBody())
+		var reason string
+		if err!=nil {
+			if ctx.Err()!=nil || !policy.retryError(err) {
+				return nil, err
+			}
+			reason = err.Error()
+		}else{
+			var retry bool
+			if reason, retry = policy.retryResponse(h.statusCode, h.rspHeaders, body); !retry {
+				return body, nil
+			}
+		}
+		if attempt>=policy.MaxAttempts {
+			return body, err
+		}
+		backoff := policy.Backoff(attempt)
+		log.Module("net").WithFields(log.Fields{"url": url, "attempt": attempt, "backoff": backoff.String()}).
+			Warnf("请求失败，重试: %s", reason)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// requestOnce 发送一次请求并读取响应内容
+func (h *HttpClient) requestOnce(ctx context.Context, url string, reqBody io.Reader) (body []byte, err error){
+	rsp, err := h.doRequest(ctx, url, reqBody)
 	if err!=nil {
 		return nil, err
 	}
 	h.extractRspHeaders(rsp)
 	defer core.CloseQuietly(rsp.Body)
-	body, err = ioutil.ReadAll(rsp.Body)
-	return body, err
+	return ioutil.ReadAll(rsp.Body)
 }
 
-// RequestStream 发送请求，调用成功后可使用 ResponseHeaders方法获取响应头
+// RequestStream 发送请求并将响应内容写入dst，适用于下载文件等较大的响应，调用成功后可使用 ResponseHeaders方法获取响应头
+// 响应内容边读取边写入，无法重新发送，因此不使用重试策略
 //    url: 请求地址
+//    dst: 写入响应内容
 // return
-//    body: 响应内容
+//    written: 写入的字节数
+//    err: 请求过程中出现的错误
+func (h *HttpClient) RequestStream(url string, dst io.Writer) (written int64, err error){
+	return h.RequestStreamContext(context.Background(), url, dst)
+}
+
+// RequestStreamContext 与 RequestStream 相同，ctx取消或超时后立即中断请求与响应内容的读取，不使用重试策略
+//    ctx: 请求的context
+//    url: 请求地址
+//    dst: 写入响应内容
+// return
+//    written: 写入的字节数
 //    err: 请求过程中出现的错误
-func (h *HttpClient) RequestStream(url string) (body io.Writer, err error){
-	rsp, err := h.doRequest(url)
+func (h *HttpClient) RequestStreamContext(ctx context.Context, url string, dst io.Writer) (written int64, err error){
+	if h.requestTimeout>0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.requestTimeout)
+		defer cancel()
+	}
+	rsp, err := h.doRequest(ctx, url, h.body)
 	if err!=nil {
-		return nil, err
+		return 0, err
 	}
 	h.extractRspHeaders(rsp)
 	defer core.CloseQuietly(rsp.Body)
-
-	var bodyWriter bufio.Writer
-	_, err = io.Copy(&bodyWriter, rsp.Body)
-	return body, err
+	return io.Copy(dst, rsp.Body)
 }
 
 // ResponseHeaders 请求完成后，使用此方法获取响应头
@@ -310,6 +429,11 @@ func (h *HttpClient) ResponseHeaders() map[string][]string{
 	return h.rspHeaders
 }
 
+// StatusCode 请求完成后，使用此方法获取响应状态码
+func (h *HttpClient) StatusCode() int{
+	return h.statusCode
+}
+
 // FastGet 发送简单的GET请求，注意，调用该方法发送请求后 ResponseHeaders方法不会获取响应头
 //    url: 请求地址
 // return
@@ -0,0 +1,487 @@
+package net
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/core/paths"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAccount 未指定账号时使用的账号
+const DefaultAccount = "default"
+
+// netscapeHeader Netscape cookies.txt 文件的第一行
+const netscapeHeader = "# Netscape HTTP Cookie File"
+
+// netscapeHttpOnly Netscape格式中HttpOnly的cookie所在行的前缀
+const netscapeHttpOnly = "#HttpOnly_"
+
+// CookieFile 账号的cookie文件，位于数据目录的cookies目录中，
+// 文件名中字母、数字与 . _ - @ 之外的字符(包括%)转换为 %XX，不同的账号不会使用同一个文件
+//    account: 账号，为空时使用 DefaultAccount
+func CookieFile(account string) string{
+	if account=="" {
+		account = DefaultAccount
+	}
+	var name strings.Builder
+	for i := 0; i<len(account); i++ {
+		c := account[i]
+		if c>='a' && c<='z' || c>='A' && c<='Z' || c>='0' && c<='9' || c=='.' || c=='_' || c=='-' || c=='@' {
+			name.WriteByte(c)
+		}else{
+			fmt.Fprintf(&name, "%%%02X", c)
+		}
+	}
+	return filepath.Join(paths.DataDir(), "cookies", name.String() + ".json")
+}
+
+var (
+	jarLock sync.Mutex
+	jars = make(map[string]*CookieJar)
+)
+
+// AccountJar 获取账号的cookie，同一账号在程序中共用一个CookieJar，第一次获取时从文件中读取
+//    account: 账号，为空时使用 DefaultAccount
+func AccountJar(account string) (*CookieJar, error){
+	if account=="" {
+		account = DefaultAccount
+	}
+	jarLock.Lock()
+	defer jarLock.Unlock()
+	if jar, ok := jars[account]; ok {
+		return jar, nil
+	}
+	jar := NewCookieJar(CookieFile(account))
+	if err := jar.Load(); err!=nil {
+		return nil, err
+	}
+	jars[account] = jar
+	return jar, nil
+}
+
+// NewCookieJar 创建cookie存储，需要调用Load读取已保存的cookie
+//    file: 保存cookie的文件，为空时只保存在内存中
+func NewCookieJar(file string) *CookieJar{
+	return &CookieJar{file: file, entries: make(map[string]*cookieEntry)}
+}
+
+// CookieJar 实现http.CookieJar，按domain、path与过期时间选择发送的cookie，
+// 收到的cookie修改后立即写入文件，会话cookie(未设置过期时间)也会保存，重启后继续使用
+type CookieJar struct {
+	lock sync.Mutex
+	file string
+	//以 domain;path;name 为键
+	entries map[string]*cookieEntry
+	//创建cookie的序号，用于相同path长度的cookie排序
+	seq uint64
+}
+
+// cookieEntry 保存的cookie
+type cookieEntry struct {
+	Name string 		`json:"name"`
+	Value string 		`json:"value"`
+	Domain string 		`json:"domain"`
+	Path string 		`json:"path"`
+	// 过期时间，为空时是会话cookie
+	Expires *time.Time 	`json:"expires,omitempty"`
+	Secure bool 		`json:"secure,omitempty"`
+	HttpOnly bool 		`json:"httpOnly,omitempty"`
+	// 是否只发送给domain本身，不包括子域名
+	HostOnly bool 		`json:"hostOnly,omitempty"`
+	seq uint64
+}
+
+func (e *cookieEntry) key() string{
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e *cookieEntry) expired(now time.Time) bool{
+	return e.Expires!=nil && !e.Expires.After(now)
+}
+
+// File 保存cookie的文件
+func (j *CookieJar) File() string{
+	return j.file
+}
+
+// Load 读取已保存的cookie，文件不存在时为空，过期的cookie会被丢弃
+func (j *CookieJar) Load() error{
+	if j.file=="" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(j.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err!=nil {
+		return fmt.Errorf("读取cookie失败: %w", err)
+	}
+	var entries []*cookieEntry
+	if err = json.Unmarshal(data, &entries); err!=nil {
+		return fmt.Errorf("解析cookie失败: %s, %w", j.file, err)
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.entries = make(map[string]*cookieEntry, len(entries))
+	now := time.Now()
+	for _, e := range entries {
+		if !e.expired(now) {
+			j.add(e)
+		}
+	}
+	return nil
+}
+
+// Save 将未过期的cookie写入文件
+func (j *CookieJar) Save() error{
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.save()
+}
+
+func (j *CookieJar) save() error{
+	if j.file=="" {
+		return nil
+	}
+	data, err := json.MarshalIndent(j.sorted(time.Now()), "", "  ")
+	if err!=nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(j.file), 0700); err!=nil {
+		return err
+	}
+	if err = core.WriteFileAtomic(j.file, data, 0600); err!=nil {
+		return fmt.Errorf("保存cookie失败: %w", err)
+	}
+	return nil
+}
+
+// sorted 未过期的cookie，按domain、path与创建顺序排列
+func (j *CookieJar) sorted(now time.Time) []*cookieEntry{
+	entries := make([]*cookieEntry, 0, len(j.entries))
+	for _, e := range j.entries {
+		if !e.expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].Domain!=entries[b].Domain {
+			return entries[a].Domain < entries[b].Domain
+		}
+		if entries[a].Path!=entries[b].Path {
+			return entries[a].Path < entries[b].Path
+		}
+		return entries[a].seq < entries[b].seq
+	})
+	return entries
+}
+
+func (j *CookieJar) add(e *cookieEntry){
+	if old, ok := j.entries[e.key()]; ok {
+		//替换时保持原有的顺序
+		e.seq = old.seq
+	}else{
+		j.seq++
+		e.seq = j.seq
+	}
+	j.entries[e.key()] = e
+}
+
+// SetCookies 保存响应中的cookie，domain与请求的域名不匹配的cookie会被忽略，已过期的cookie会删除已保存的同名cookie
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie){
+	host := canonicalHost(u.Host)
+	if host=="" || len(cookies)==0 {
+		return
+	}
+	now := time.Now()
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	changed := false
+	for _, cookie := range cookies {
+		e, ok := newEntry(host, u.Path, cookie, now)
+		if !ok {
+			continue
+		}
+		if e.expired(now) {
+			if _, exists := j.entries[e.key()]; exists {
+				delete(j.entries, e.key())
+				changed = true
+			}
+			continue
+		}
+		j.add(e)
+		changed = true
+	}
+	if changed {
+		if err := j.save(); err!=nil {
+			log.Module("net").Warnf("保存cookie失败: %s, %s", j.file, err)
+		}
+	}
+}
+
+// newEntry 根据响应中的cookie创建保存的cookie，domain不匹配时返回false
+func newEntry(host, requestPath string, cookie *http.Cookie, now time.Time) (*cookieEntry, bool){
+	if cookie.Name=="" {
+		return nil, false
+	}
+	e := &cookieEntry{
+		Name: cookie.Name,
+		Value: cookie.Value,
+		Secure: cookie.Secure,
+		HttpOnly: cookie.HttpOnly,
+	}
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if domain=="" || domain==host {
+		e.Domain = host
+		e.HostOnly = domain==""
+	}else{
+		//不允许ip地址与公共后缀，domain必须是请求的域名或其上级域名
+		if net.ParseIP(host)!=nil || isPublicSuffix(domain) || !strings.HasSuffix(host, "." + domain) {
+			return nil, false
+		}
+		e.Domain = domain
+	}
+	e.Path = cookie.Path
+	if e.Path=="" || e.Path[0]!='/' {
+		e.Path = defaultPath(requestPath)
+	}
+	if cookie.MaxAge<0 {
+		expires := time.Unix(0, 0)
+		e.Expires = &expires
+	}else if cookie.MaxAge>0 {
+		expires := now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		e.Expires = &expires
+	}else if !cookie.Expires.IsZero() {
+		expires := cookie.Expires
+		e.Expires = &expires
+	}
+	return e, true
+}
+
+// publicSuffixes 不允许设置为cookie的domain的公共后缀，只包含常用的二级后缀，顶级域名由 isPublicSuffix 判断
+var publicSuffixes = map[string]bool{
+	"com.cn": true, "net.cn": true, "org.cn": true, "gov.cn": true, "edu.cn": true, "ac.cn": true, "mil.cn": true,
+	"bj.cn": true, "sh.cn": true, "tj.cn": true, "cq.cn": true, "he.cn": true, "sx.cn": true, "nm.cn": true,
+	"ln.cn": true, "jl.cn": true, "hl.cn": true, "js.cn": true, "zj.cn": true, "ah.cn": true, "fj.cn": true,
+	"jx.cn": true, "sd.cn": true, "ha.cn": true, "hb.cn": true, "hn.cn": true, "gd.cn": true, "gx.cn": true,
+	"hi.cn": true, "sc.cn": true, "gz.cn": true, "yn.cn": true, "xz.cn": true, "sn.cn": true, "gs.cn": true,
+	"qh.cn": true, "nx.cn": true, "xj.cn": true, "tw.cn": true, "hk.cn": true, "mo.cn": true,
+	"com.hk": true, "net.hk": true, "org.hk": true, "edu.hk": true, "gov.hk": true,
+	"com.tw": true, "net.tw": true, "org.tw": true, "edu.tw": true, "gov.tw": true,
+	"com.mo": true, "net.mo": true, "org.mo": true,
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true,
+	"co.jp": true, "ne.jp": true, "or.jp": true, "ac.jp": true, "go.jp": true,
+	"co.kr": true, "or.kr": true, "com.sg": true, "com.au": true, "net.au": true, "org.au": true,
+}
+
+// isPublicSuffix 是否是顶级域名或公共后缀，如 cn、com.cn
+func isPublicSuffix(domain string) bool{
+	return !strings.Contains(domain, ".") || publicSuffixes[domain]
+}
+
+// defaultPath 未设置path时使用请求路径所在的目录
+func defaultPath(p string) string{
+	if p=="" || p[0]!='/' {
+		return "/"
+	}
+	i := strings.LastIndex(p, "/")
+	if i==0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// Cookies 发送请求时使用的cookie，path较长的cookie在前
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie{
+	host := canonicalHost(u.Host)
+	if host=="" {
+		return nil
+	}
+	requestPath := u.Path
+	if requestPath=="" {
+		requestPath = "/"
+	}
+	secure := u.Scheme=="https"
+	now := time.Now()
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	var selected []*cookieEntry
+	for _, e := range j.entries {
+		if e.expired(now) || (e.Secure && !secure) {
+			continue
+		}
+		if !domainMatch(e, host) || !pathMatch(e.Path, requestPath) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path)!=len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].seq < selected[b].seq
+	})
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+func domainMatch(e *cookieEntry, host string) bool{
+	if e.HostOnly {
+		return host==e.Domain
+	}
+	return host==e.Domain || strings.HasSuffix(host, "." + e.Domain)
+}
+
+func pathMatch(cookiePath, requestPath string) bool{
+	if cookiePath==requestPath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)]=='/'
+}
+
+// canonicalHost 去掉端口并转换为小写的域名
+func canonicalHost(host string) string{
+	if h, _, err := net.SplitHostPort(host); err==nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// All 所有未过期的cookie，包括domain、path与过期时间
+func (j *CookieJar) All() []*http.Cookie{
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	entries := j.sorted(time.Now())
+	cookies := make([]*http.Cookie, 0, len(entries))
+	for _, e := range entries {
+		cookie := &http.Cookie{
+			Name: e.Name,
+			Value: e.Value,
+			Domain: e.Domain,
+			Path: e.Path,
+			Secure: e.Secure,
+			HttpOnly: e.HttpOnly,
+		}
+		if e.Expires!=nil {
+			cookie.Expires = *e.Expires
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+// Clear 删除所有cookie，如退出登录
+func (j *CookieJar) Clear() error{
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.entries = make(map[string]*cookieEntry)
+	return j.save()
+}
+
+// ImportNetscape 从Netscape cookies.txt格式中导入cookie，与已有的同名cookie合并，可用于从浏览器中导入登录状态
+// return
+//    int: 导入的cookie数量，已过期的cookie不计算在内
+//    error: 格式错误，错误信息中包含行号
+func (j *CookieJar) ImportNetscape(r io.Reader) (int, error){
+	var entries []*cookieEntry
+	now := time.Now()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(text, netscapeHttpOnly)
+		if httpOnly {
+			text = text[len(netscapeHttpOnly):]
+		}
+		if strings.TrimSpace(text)=="" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields)!=7 {
+			return 0, fmt.Errorf("第%d行格式错误，应为以tab分隔的7列", line)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err!=nil {
+			return 0, fmt.Errorf("第%d行的过期时间格式错误: %s", line, fields[4])
+		}
+		domain := strings.ToLower(fields[0])
+		e := &cookieEntry{
+			Domain: strings.TrimPrefix(domain, "."),
+			HostOnly: strings.ToUpper(fields[1])!="TRUE",
+			Path: fields[2],
+			Secure: strings.ToUpper(fields[3])=="TRUE",
+			Name: fields[5],
+			Value: fields[6],
+			HttpOnly: httpOnly,
+		}
+		if e.Path=="" {
+			e.Path = "/"
+		}
+		if expires>0 {
+			t := time.Unix(expires, 0)
+			e.Expires = &t
+		}
+		if !e.expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err!=nil {
+		return 0, err
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	for _, e := range entries {
+		j.add(e)
+	}
+	return len(entries), j.save()
+}
+
+// ExportNetscape 以Netscape cookies.txt格式导出所有未过期的cookie，会话cookie的过期时间为0
+func (j *CookieJar) ExportNetscape(w io.Writer) error{
+	j.lock.Lock()
+	entries := j.sorted(time.Now())
+	j.lock.Unlock()
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%s\n\n", netscapeHeader); err!=nil {
+		return err
+	}
+	for _, e := range entries {
+		prefix := ""
+		if e.HttpOnly {
+			prefix = netscapeHttpOnly
+		}
+		domain, subdomains := e.Domain, "FALSE"
+		if !e.HostOnly {
+			domain, subdomains = "." + e.Domain, "TRUE"
+		}
+		var expires int64
+		if e.Expires!=nil {
+			expires = e.Expires.Unix()
+		}
+		_, err := fmt.Fprintf(bw, "%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n", prefix, domain, subdomains, e.Path,
+			strings.ToUpper(strconv.FormatBool(e.Secure)), expires, e.Name, e.Value)
+		if err!=nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,176 @@
+package net
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// BusyText 12306服务繁忙时返回的页面中包含的内容
+const BusyText = "网络繁忙"
+
+// DefaultRetryStatus 默认重试的响应状态码
+var DefaultRetryStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy 默认的重试策略：最多请求3次，等待时间从200ms开始翻倍，最长5s，
+// 请求超时、连接被重置等网络错误，DefaultRetryStatus 中的状态码，以及12306的网络繁忙页面会重试
+func DefaultRetryPolicy() *RetryPolicy{
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff: 5 * time.Second,
+		Multiplier: 2,
+		Jitter: 0.2,
+		RetryStatus: DefaultRetryStatus,
+		RetryError: IsTransientError,
+		RetryBody: IsBusyPage,
+	}
+}
+
+// RetryPolicy HttpClient请求失败时的重试策略，默认不重试非幂等的请求方法(POST、PATCH、CONNECT)
+type RetryPolicy struct {
+	// 最多请求的次数，包括第一次请求，小于等于1时不重试
+	MaxAttempts int
+	// 第一次重试前的等待时间
+	InitialBackoff time.Duration
+	// 最长的等待时间，0表示不限制
+	MaxBackoff time.Duration
+	// 每次重试后等待时间的倍数，小于1时使用1
+	Multiplier float64
+	// 等待时间随机浮动的比例，0~1，如0.2表示在等待时间的80%~120%之间，避免多个请求同时重试
+	Jitter float64
+	// 需要重试的响应状态码
+	RetryStatus []int
+	// 请求出错时是否重试，为空时不重试
+	RetryError func(err error) bool
+	// 根据响应状态码、响应头与响应内容判断是否重试，为空时只按RetryStatus判断
+	RetryBody func(statusCode int, header http.Header, body []byte) bool
+	// 是否重试非幂等的请求方法
+	RetryNonIdempotent bool
+}
+
+// Backoff 第attempt次重试前的等待时间，attempt从1开始
+func (p *RetryPolicy) Backoff(attempt int) time.Duration{
+	if attempt<1 || p.InitialBackoff<=0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier<1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff>0 && backoff>float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter>0 {
+		jitter := math.Min(p.Jitter, 1)
+		backoff = backoff * (1 - jitter + 2 * jitter * random())
+	}
+	return time.Duration(backoff)
+}
+
+// Retryable 请求方法是否可以重试
+func (p *RetryPolicy) Retryable(method string) bool{
+	if p.MaxAttempts<=1 {
+		return false
+	}
+	return p.RetryNonIdempotent || IsIdempotent(method)
+}
+
+// retryError 请求出错时是否重试
+func (p *RetryPolicy) retryError(err error) bool{
+	return p.RetryError!=nil && p.RetryError(err)
+}
+
+// retryResponse 根据响应判断是否重试，需要重试时返回重试的原因
+func (p *RetryPolicy) retryResponse(statusCode int, header http.Header, body []byte) (string, bool){
+	for _, status := range p.RetryStatus {
+		if status==statusCode {
+			return strconv.Itoa(statusCode) + " " + http.StatusText(statusCode), true
+		}
+	}
+	if p.RetryBody==nil || !p.RetryBody(statusCode, header, body) {
+		return "", false
+	}
+	if IsBusyPage(statusCode, header, body) {
+		return "网络繁忙页面", true
+	}
+	return "响应内容满足重试条件", true
+}
+
+// IsIdempotent 请求方法是否是幂等的，重复请求不会产生额外的影响
+func IsIdempotent(method string) bool{
+	switch HttpMethod(strings.ToUpper(method)) {
+	case HttpGet, HttpHead, HttpPut, HttpDelete, HttpOptions, HttpTrace:
+		return true
+	}
+	return false
+}
+
+// IsTransientError 是否是重试后可能成功的网络错误，如超时、连接被拒绝或被重置、连接意外断开
+func IsTransientError(err error) bool{
+	if err==nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsBusyPage 是否是12306返回的网络繁忙页面，只判断html格式的响应，json等接口数据中包含该内容时不算
+func IsBusyPage(statusCode int, header http.Header, body []byte) bool{
+	contentType := header.Get("Content-Type")
+	if contentType=="" {
+		contentType = http.DetectContentType(body)
+	}
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "text/html") {
+		return false
+	}
+	return bytes.Contains(body, []byte(BusyText))
+}
+
+var (
+	randLock sync.Mutex
+	randSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func random() float64{
+	randLock.Lock()
+	defer randLock.Unlock()
+	return randSource.Float64()
+}
+
+// rewindBody 将请求体读取到内存中，每次调用返回的函数都得到从头开始读取的请求体，用于重试时重新发送
+func rewindBody(body io.Reader) (func() io.Reader, error){
+	if body==nil {
+		return func() io.Reader { return nil }, nil
+	}
+	data, err := ioutil.ReadAll(body)
+	if err!=nil {
+		return nil, err
+	}
+	return func() io.Reader {
+		return bytes.NewReader(data)
+	}, nil
+}
@@ -0,0 +1,174 @@
+// Package paths 解析应用使用的配置、数据、缓存、日志与静态资源目录
+//
+// 用户目录遵循 XDG Base Directory 规范：
+//	配置目录: $XDG_CONFIG_HOME/desktop-app，未设置时为 ~/.config/desktop-app
+//	数据目录: $XDG_DATA_HOME/desktop-app，未设置时为 ~/.local/share/desktop-app
+//	缓存目录: $XDG_CACHE_HOME/desktop-app，未设置时为 ~/.cache/desktop-app
+//	日志目录: 数据目录下的 log
+// 非Linux系统在未设置XDG环境变量时，使用 os.UserConfigDir 与 os.UserCacheDir 返回的目录
+//
+// 查找配置文件时按以下顺序，先找到的优先：
+//	1. 用户配置目录
+//	2. 程序所在目录下的 config
+//	3. 当前工作目录下的 config
+//	4. $XDG_CONFIG_DIRS 中每个目录下的 desktop-app，未设置时为 /etc/xdg/desktop-app
+//
+// 查找静态资源(ui目录)时按以下顺序，先找到的优先：
+//	1. 程序所在目录下的 ui
+//	2. 当前工作目录下的 ui
+//	3. 数据目录下的 ui
+//	4. $XDG_DATA_DIRS 中每个目录下的 desktop-app/ui，未设置时为 /usr/local/share 与 /usr/share
+package paths
+
+import (
+	"errors"
+	"github.com/abeir/desktop-app/core"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AppName 应用在用户目录中使用的目录名
+const AppName = "desktop-app"
+
+// 资源目录中需要包含的子目录，用来判断资源目录是否有效
+const templateDir = "template"
+
+// ConfigDir 用户配置目录，首次运行时生成的配置文件写入此目录
+func ConfigDir() string{
+	return userDir("XDG_CONFIG_HOME", ".config", os.UserConfigDir)
+}
+
+// DataDir 用户数据目录
+func DataDir() string{
+	return userDir("XDG_DATA_HOME", filepath.Join(".local", "share"), os.UserConfigDir)
+}
+
+// CacheDir 用户缓存目录
+func CacheDir() string{
+	return userDir("XDG_CACHE_HOME", ".cache", os.UserCacheDir)
+}
+
+// LogDir 默认的日志目录
+func LogDir() string{
+	return filepath.Join(DataDir(), "log")
+}
+
+// ConfigDirs 查找配置文件的目录，按优先级从高到低排列
+func ConfigDirs() []string{
+	dirs := []string{ConfigDir()}
+	if exeDir, err := core.CurrentPath(); err==nil {
+		dirs = append(dirs, filepath.Join(exeDir, "config"))
+	}
+	if wd, err := os.Getwd(); err==nil {
+		dirs = append(dirs, filepath.Join(wd, "config"))
+	}
+	for _, dir := range systemDirs("XDG_CONFIG_DIRS", "/etc/xdg") {
+		dirs = append(dirs, filepath.Join(dir, AppName))
+	}
+	return unique(dirs)
+}
+
+// FindConfigFile 在 ConfigDirs 中依次查找配置文件
+// param
+//    names: 文件名，如 application.yml，同一目录中按names的顺序查找
+// return
+//    string: 找到的第一个文件，未找到时返回空字符串
+func FindConfigFile(names ...string) string{
+	for _, dir := range ConfigDirs() {
+		for _, name := range names {
+			file := filepath.Join(dir, name)
+			if core.IsExists(file) {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// AssetDirs 查找静态资源的目录，按优先级从高到低排列
+func AssetDirs() []string{
+	var dirs []string
+	if exeDir, err := core.CurrentPath(); err==nil {
+		dirs = append(dirs, filepath.Join(exeDir, "ui"))
+	}
+	if wd, err := os.Getwd(); err==nil {
+		dirs = append(dirs, filepath.Join(wd, "ui"))
+	}
+	dirs = append(dirs, filepath.Join(DataDir(), "ui"))
+	for _, dir := range systemDirs("XDG_DATA_DIRS", "/usr/local/share:/usr/share") {
+		dirs = append(dirs, filepath.Join(dir, AppName, "ui"))
+	}
+	return unique(dirs)
+}
+
+// AssetDir 静态资源目录，返回 AssetDirs 中第一个包含template目录的目录
+func AssetDir() (string, error){
+	dirs := AssetDirs()
+	for _, dir := range dirs {
+		if core.IsExists(filepath.Join(dir, templateDir)) {
+			return dir, nil
+		}
+	}
+	return "", errors.New("未找到静态资源目录，已查找: " + strings.Join(dirs, ", "))
+}
+
+// EnsureDirs 创建用户配置、数据、缓存与日志目录，已存在的目录不做处理
+func EnsureDirs() error{
+	for _, dir := range []string{ConfigDir(), DataDir(), CacheDir(), LogDir()} {
+		if err := os.MkdirAll(dir, 0700); err!=nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userDir 获取用户目录，优先使用XDG环境变量，其次在Linux下使用家目录中的默认位置，最后使用系统提供的目录
+//    xdgVar: XDG环境变量名
+//    homeRel: 相对于家目录的默认位置
+//    fallback: 系统提供的目录
+func userDir(xdgVar, homeRel string, fallback func() (string, error)) string{
+	if dir := os.Getenv(xdgVar); dir!="" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName)
+	}
+	if core.Os()==core.OsLinux || core.Os()==core.OsUnknow {
+		if home, err := os.UserHomeDir(); err==nil {
+			return filepath.Join(home, homeRel, AppName)
+		}
+	}else if dir, err := fallback(); err==nil {
+		return filepath.Join(dir, AppName)
+	}
+	return filepath.Join(os.TempDir(), AppName)
+}
+
+// systemDirs 解析以 : 分隔的XDG系统目录列表，未设置时使用默认值
+func systemDirs(xdgVar, defaultValue string) []string{
+	value := os.Getenv(xdgVar)
+	if value=="" {
+		if core.Os()==core.OsWindows {
+			return nil
+		}
+		value = defaultValue
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(value) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func unique(dirs []string) []string{
+	seen := make(map[string]bool, len(dirs))
+	result := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		result = append(result, dir)
+	}
+	return result
+}
@@ -0,0 +1,289 @@
+// Package preference 用户在运行时修改的偏好设置，以json格式保存在用户数据目录中，与只读的application.yml分开
+package preference
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/abeir/desktop-app/core/paths"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 主题
+const (
+	ThemeSystem = "system"
+	ThemeLight = "light"
+	ThemeDark = "dark"
+)
+
+// DefaultFile 偏好设置文件的默认位置
+func DefaultFile() string{
+	return filepath.Join(paths.DataDir(), "preferences.json")
+}
+
+// Preferences 用户偏好设置
+type Preferences struct {
+	// 默认出发站
+	FromStation string 	`json:"fromStation"`
+	// 默认到达站
+	ToStation string 	`json:"toStation"`
+	// 优先选择的席别，按优先级排列
+	SeatTypes []string 	`json:"seatTypes"`
+	// 主题：system、light、dark
+	Theme string 		`json:"theme"`
+	Notification Notification 	`json:"notification"`
+}
+
+// Notification 通知设置
+type Notification struct {
+	// 是否启用通知
+	Enabled bool 	`json:"enabled"`
+	// 通知时是否播放提示音
+	Sound bool 		`json:"sound"`
+}
+
+// Defaults 偏好设置的默认值，文件中未设置的项使用默认值
+func Defaults() Preferences{
+	return Preferences{
+		SeatTypes: []string{},
+		Theme: ThemeSystem,
+		Notification: Notification{
+			Enabled: true,
+			Sound: true,
+		},
+	}
+}
+
+// Validate 校验偏好设置
+func (p *Preferences) Validate() error{
+	switch p.Theme {
+	case ThemeSystem, ThemeLight, ThemeDark:
+	default:
+		return fmt.Errorf("theme: 不支持的主题: %s，可选值: %s, %s, %s", p.Theme, ThemeSystem, ThemeLight, ThemeDark)
+	}
+	for i, seat := range p.SeatTypes {
+		if seat=="" {
+			return fmt.Errorf("seatTypes[%d]: 席别不能为空", i)
+		}
+	}
+	return nil
+}
+
+// clone 复制偏好设置，避免调用方修改切片影响已保存的值
+func (p Preferences) clone() Preferences{
+	p.SeatTypes = append([]string{}, p.SeatTypes...)
+	return p
+}
+
+// ChangeEvent 偏好设置变化事件
+type ChangeEvent struct {
+	Old Preferences
+	New Preferences
+}
+
+// ChangeListener 偏好设置变化的监听程序
+type ChangeListener func(event *ChangeEvent)
+
+// Migrations 偏好设置文件的升级函数，修改 Preferences 的结构时在此注册
+var Migrations = migrate.NewRegistry("preferences")
+
+// NewStore 创建偏好设置存储，需要调用Load读取已保存的设置
+//    file: 偏好设置文件，通常为 DefaultFile()
+func NewStore(file string) *Store{
+	return &Store{file: file, prefs: Defaults(), migrations: Migrations}
+}
+
+// Store 偏好设置存储，修改后立即写入文件并通知监听程序，可在多个goroutine中使用
+type Store struct {
+	lock sync.RWMutex
+	file string
+	prefs Preferences
+	listeners []ChangeListener
+	//升级文件使用的升级函数
+	migrations *migrate.Registry
+	//加载时执行的升级函数
+	migrated []migrate.Migration
+	//加载文件失败的错误，不为空时不能修改，避免使用默认值覆盖用户原有的文件
+	loadErr error
+}
+
+// SetMigrations 设置升级偏好设置文件使用的升级函数，需要在Load之前调用，默认为 Migrations
+func (s *Store) SetMigrations(registry *migrate.Registry) *Store{
+	s.migrations = registry
+	return s
+}
+
+// Migrations 加载时执行的升级函数，文件已是当前版本时为空
+func (s *Store) Migrations() []migrate.Migration{
+	return s.migrated
+}
+
+// File 偏好设置文件
+func (s *Store) File() string{
+	return s.file
+}
+
+// Load 读取偏好设置文件，文件不存在时使用默认值，旧版本的文件备份后升级到当前版本
+// 文件存在但加载失败时使用默认值，并且在重新加载成功之前不能修改，原文件保持不变
+func (s *Store) Load() error{
+	err := s.load()
+	s.lock.Lock()
+	s.loadErr = err
+	s.lock.Unlock()
+	return err
+}
+
+// LoadError 加载文件失败的错误，不为空时偏好设置为只读
+func (s *Store) LoadError() error{
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.loadErr
+}
+
+func (s *Store) load() error{
+	data, err := ioutil.ReadFile(s.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err!=nil {
+		return fmt.Errorf("读取偏好设置失败: %w", err)
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(data, &doc); err!=nil {
+		return fmt.Errorf("解析偏好设置失败: %s, %w", s.file, err)
+	}
+	version, err := migrate.Version(doc)
+	if err!=nil {
+		return fmt.Errorf("%s: %w", s.file, err)
+	}
+	applied, err := s.migrations.Migrate(doc)
+	if err!=nil {
+		return fmt.Errorf("%s: %w", s.file, err)
+	}
+	delete(doc, migrate.VersionKey)
+	if data, err = json.Marshal(doc); err!=nil {
+		return err
+	}
+	prefs := Defaults()
+	if err = decode(data, &prefs); err!=nil {
+		return fmt.Errorf("解析偏好设置失败: %s, %w", s.file, err)
+	}
+	if err = prefs.Validate(); err!=nil {
+		return fmt.Errorf("偏好设置有误: %s, %w", s.file, err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(applied)>0 {
+		if _, err = migrate.Backup(s.file, version); err!=nil {
+			return err
+		}
+		if err = s.save(prefs); err!=nil {
+			return err
+		}
+		s.migrated = applied
+	}
+	s.prefs = prefs
+	return nil
+}
+
+// Get 当前的偏好设置
+func (s *Store) Get() Preferences{
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.prefs.clone()
+}
+
+// Set 替换全部偏好设置
+func (s *Store) Set(prefs Preferences) error{
+	return s.update(func(Preferences) (Preferences, error) {
+		return prefs.clone(), nil
+	})
+}
+
+// Replace 使用json替换全部偏好设置，json中未设置的项使用默认值
+func (s *Store) Replace(data []byte) error{
+	return s.update(func(Preferences) (Preferences, error) {
+		prefs := Defaults()
+		return prefs, decode(data, &prefs)
+	})
+}
+
+// Patch 使用json修改部分偏好设置，json中未设置的项保持不变
+func (s *Store) Patch(data []byte) error{
+	return s.update(func(current Preferences) (Preferences, error) {
+		return current, decode(data, &current)
+	})
+}
+
+// OnChange 添加偏好设置变化的监听程序
+func (s *Store) OnChange(listener ChangeListener) *Store{
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.listeners = append(s.listeners, listener)
+	return s
+}
+
+// update 修改偏好设置，校验通过并写入文件后才会生效
+func (s *Store) update(modify func(current Preferences) (Preferences, error)) error{
+	s.lock.Lock()
+	if s.loadErr!=nil {
+		s.lock.Unlock()
+		return fmt.Errorf("偏好设置文件加载失败，修复或删除文件后才能修改: %w", s.loadErr)
+	}
+	old := s.prefs.clone()
+	prefs, err := modify(s.prefs.clone())
+	if err==nil {
+		err = prefs.Validate()
+	}
+	if err==nil {
+		err = s.save(prefs)
+	}
+	if err!=nil {
+		s.lock.Unlock()
+		return err
+	}
+	s.prefs = prefs
+	listeners := s.listeners
+	s.lock.Unlock()
+
+	event := &ChangeEvent{Old: old, New: prefs.clone()}
+	for _, listener := range listeners {
+		listener(event)
+	}
+	return nil
+}
+
+// save 写入偏好设置，文件中记录当前版本
+func (s *Store) save(prefs Preferences) error{
+	data, err := json.Marshal(prefs)
+	if err!=nil {
+		return err
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(data, &doc); err!=nil {
+		return err
+	}
+	doc[migrate.VersionKey] = s.migrations.Current()
+	if data, err = json.MarshalIndent(doc, "", "  "); err!=nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.file), 0700); err!=nil {
+		return err
+	}
+	if err = core.WriteFileAtomic(s.file, data, 0600); err!=nil {
+		return fmt.Errorf("保存偏好设置失败: %w", err)
+	}
+	return nil
+}
+
+// decode 解析json到prefs中，不允许未知的设置项
+func decode(data []byte, prefs *Preferences) error{
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(prefs)
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// SecretNames 敏感信息的名称，不区分大小写
+// 日志中的字段、请求头与参数，以及 config show 中的配置项、请求头与参数都按这份名单遮盖
+var SecretNames = []string{
+	"password", "passwd", "pwd", "secret", "token", "credential", "apikey", "privatekey",
+	"tk", "uamtk", "jsessionid",
+	"cookie", "set-cookie", "authorization", "proxy-authorization",
+}
+
+// SecretNamePattern SecretNames 组成的正则表达式分组，如 (?:password|passwd|...)
+func SecretNamePattern() string{
+	quoted := make([]string, 0, len(SecretNames))
+	for _, name := range SecretNames {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "(?:" + strings.Join(quoted, "|") + ")"
+}
+
+// IsSecretName 名称是否为敏感信息，与 SecretNames 中的名称相同，或者以其结尾且是单独的单词，
+// 如 dbPassword、access_token、X-Auth-Token，而 stk 不是
+//   name: 字段名、配置项名、请求头或参数名
+func IsSecretName(name string) bool{
+	lower := strings.ToLower(name)
+	if len(lower)!=len(name) {
+		return false
+	}
+	for _, secret := range SecretNames {
+		if lower==secret {
+			return true
+		}
+		if !strings.HasSuffix(lower, secret) {
+			continue
+		}
+		start := len(name) - len(secret)
+		prev := rune(name[start-1])
+		if unicode.IsUpper(rune(name[start])) || prev=='_' || prev=='-' || prev=='.' {
+			return true
+		}
+	}
+	return false
+}
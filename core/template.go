@@ -1,16 +1,43 @@
 package core
 
 import (
-	"regexp"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
 	startFlag = '{'
 	endFlag = '}'
+	argFlag = ':'
 )
 
-// NewTemplate 解析模板，若模板内容中定义的参数多于实际提供的参数，则未提供的参数不会解析
+// TemplateFunc 模板中的值函数，如 {urlencode:x} 中的 urlencode
+//    arg: 冒号之后的内容，其中的参数已解析
+type TemplateFunc func(arg string) (string, error)
+
+// UnresolvedError 严格模式下模板中存在未解析的参数
+type UnresolvedError struct {
+	// 未解析的参数名，按在模板中出现的顺序排列且不重复
+	Names []string
+}
+
+func (e *UnresolvedError) Error() string{
+	return "模板中存在未解析的参数: " + strings.Join(e.Names, ", ")
+}
+
+// NewTemplate 解析模板，参数以 {name} 形式，参数名由字母、数字、_、-、. 组成，{ 之后不是参数名的内容原样保留，如json
+// 支持以下形式：
+//	{name}			参数
+//	{name:default}		参数不存在时使用默认值，默认值中可以包含参数
+//	{func:arg}		值函数，参数优先于同名的函数
+// 内置的值函数：
+//	{date:+1d|2006-01-02}	当前日期加上偏移后格式化，偏移支持 d(天) 与 time.ParseDuration 的格式，格式默认为 2006-01-02
+//	{now:unixms}		当前时间，可选 unix、unixms 或时间格式，默认为 2006-01-02 15:04:05
+//	{urlencode:x}		对x进行url编码，x中可以包含参数，如 {urlencode:{from}}
 //
 // 示例1：
 //	args := make(map[string]string)
@@ -22,54 +49,300 @@ const (
 // 示例2：
 //	args := make(map[string]string)
 //	args["url"] = "http://www.sina.com"
-//	tmpl := "{url }/11/{test}"
-//	result := core.NewTemplate().Parse(tmpl, args)
-// 结果2：http://www.sina.com/11/{test}
+//	tmpl := "{url}/11/{test}"
+//	result, err := core.NewTemplate().Strict(true).Render(tmpl, args)
+// 结果2：err 为 *UnresolvedError，Names 为 [test]
 func NewTemplate() *Template{
-	return &Template{}
+	funcs := make(map[string]TemplateFunc, len(builtinFuncs))
+	for name, fn := range builtinFuncs {
+		funcs[name] = fn
+	}
+	return &Template{funcs: funcs}
 }
 
 type Template struct {
+	// 严格模式，存在未解析的参数时返回错误
+	strict bool
+	funcs map[string]TemplateFunc
+}
 
+var builtinFuncs = map[string]TemplateFunc{
+	"date": dateFunc,
+	"now": nowFunc,
+	"urlencode": urlencodeFunc,
 }
 
-func (t *Template) findArgVal(args map[string]string, argName string) string{
-	for k,v := range args {
-		reg := regexp.MustCompile(`{\s*` + k + `\s*}`)
-		if reg.MatchString(argName) {
-			return v
-		}
-	}
-	return ""
+// lookupFunc 根据参数名获取参数值
+type lookupFunc func(name string) (value string, ok bool, err error)
+
+// renderer 单次解析的状态
+type renderer struct {
+	t *Template
+	lookup lookupFunc
+	// 是否只替换参数，为true时默认值与函数原样保留
+	argsOnly bool
+	unresolved []string
 }
 
-// Parse 解析模板，若模板内容中定义的参数多于实际提供的参数，则为提供的参数不会解析
+// Strict 设置严格模式，严格模式下 Render 在存在未解析的参数时返回 *UnresolvedError
+func (t *Template) Strict(strict bool) *Template{
+	t.strict = strict
+	return t
+}
+
+// Func 注册值函数，同名时替换内置函数
+func (t *Template) Func(name string, fn TemplateFunc) *Template{
+	t.funcs[name] = fn
+	return t
+}
+
+// Parse 只替换args中存在的参数，其余占位符(包括默认值与函数)原样保留，用于分阶段解析模板
 //    tmpl: 模板内容，参数以 {a} 形式
 //    args: 模板参数
 func (t *Template) Parse(tmpl string, args map[string]string) string{
-	canPush := false
-	argNameStack := MakeStringStack()
+	r := &renderer{t: t, lookup: mapLookup(args), argsOnly: true}
+	result, _ := r.render(tmpl)
+	return result
+}
 
-	argMap := make(map[string]string)
+// Render 解析模板，依次使用参数、值函数与默认值，非严格模式下未解析的参数原样保留
+//    tmpl: 模板内容
+//    args: 模板参数
+func (t *Template) Render(tmpl string, args map[string]string) (string, error){
+	r := &renderer{t: t, lookup: mapLookup(args)}
+	result, err := r.render(tmpl)
+	if err!=nil {
+		return "", err
+	}
+	if t.strict && len(r.unresolved)>0 {
+		return "", &UnresolvedError{Names: r.unresolved}
+	}
+	return result, nil
+}
 
-	for _, s := range tmpl {
-		if s==startFlag {
-			canPush = true
+// ResolveArgs 解析参数之间的引用，如 otn: '{base}/otn' 中的 {base}，引用存在循环时返回错误
+// 引用不存在的参数原样保留，以便调用时再提供
+func (t *Template) ResolveArgs(args map[string]string) (map[string]string, error){
+	resolved := make(map[string]string, len(args))
+	resolving := make(map[string]bool)
+	var path []string
+	var lookup lookupFunc
+	lookup = func(name string) (string, bool, error) {
+		if v, ok := resolved[name]; ok {
+			return v, true, nil
+		}
+		raw, ok := args[name]
+		if !ok {
+			return "", false, nil
 		}
-		if canPush{
-			_, _ = argNameStack.PushByte(byte(s))
+		if resolving[name] {
+			return "", false, errors.New("参数引用存在循环: " + strings.Join(append(path, name), " -> "))
 		}
-		if s== endFlag {
-			canPush = false
-			argName, _ := argNameStack.Pop()
+		resolving[name] = true
+		path = append(path, name)
+		r := &renderer{t: t, lookup: lookup, argsOnly: true}
+		v, err := r.render(raw)
+		path = path[:len(path)-1]
+		resolving[name] = false
+		if err!=nil {
+			return "", false, err
+		}
+		resolved[name] = v
+		return v, true, nil
+	}
+	for name := range args {
+		if _, _, err := lookup(name); err!=nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
 
-			argMap[argName] = t.findArgVal(args, argName)
+func mapLookup(args map[string]string) lookupFunc{
+	return func(name string) (string, bool, error) {
+		v, ok := args[name]
+		return v, ok, nil
+	}
+}
+
+func (r *renderer) render(tmpl string) (string, error){
+	var result strings.Builder
+	for i := 0; i < len(tmpl); {
+		if tmpl[i]!=startFlag {
+			next := strings.IndexByte(tmpl[i:], startFlag)
+			if next<0 {
+				result.WriteString(tmpl[i:])
+				break
+			}
+			result.WriteString(tmpl[i:i+next])
+			i += next
+			continue
+		}
+		p, ok := scanPlaceholder(tmpl, i)
+		if !ok {
+			result.WriteByte(tmpl[i])
+			i++
+			continue
+		}
+		value, err := r.evaluate(p)
+		if err!=nil {
+			return "", err
 		}
+		result.WriteString(value)
+		i = p.end
+	}
+	return result.String(), nil
+}
+
+// evaluate 解析单个占位符，无法解析时返回占位符原文
+func (r *renderer) evaluate(p placeholder) (string, error){
+	value, ok, err := r.lookup(p.name)
+	if err!=nil || ok {
+		return value, err
+	}
+	if r.argsOnly {
+		return p.raw, nil
 	}
-	for k,v := range argMap {
-		if v!="" {
-			tmpl = strings.ReplaceAll(tmpl, k, v)
+	if fn, ok := r.t.funcs[p.name]; ok {
+		arg, err := r.render(p.arg)
+		if err!=nil {
+			return "", err
 		}
+		value, err = fn(arg)
+		if err!=nil {
+			return "", fmt.Errorf("模板函数 %s 执行失败: %w", p.raw, err)
+		}
+		return value, nil
+	}
+	if p.hasArg {
+		return r.render(p.arg)
+	}
+	r.addUnresolved(p.name)
+	return p.raw, nil
+}
+
+func (r *renderer) addUnresolved(name string){
+	for _, n := range r.unresolved {
+		if n==name {
+			return
+		}
+	}
+	r.unresolved = append(r.unresolved, name)
+}
+
+// placeholder 模板中的一个占位符
+type placeholder struct {
+	// 占位符原文，包括 { }
+	raw string
+	name string
+	// 冒号之后的内容
+	arg string
+	hasArg bool
+	// 占位符结束后的下标
+	end int
+}
+
+// scanPlaceholder 从 { 所在的下标开始读取占位符，{ 之后不是参数名或缺少对应的 } 时返回false
+func scanPlaceholder(tmpl string, start int) (placeholder, bool){
+	p := placeholder{}
+	i := skipSpace(tmpl, start+1)
+	nameStart := i
+	for i < len(tmpl) && isNameByte(tmpl[i], i==nameStart) {
+		i++
+	}
+	if i==nameStart {
+		return p, false
+	}
+	p.name = tmpl[nameStart:i]
+	i = skipSpace(tmpl, i)
+	if i>=len(tmpl) {
+		return p, false
+	}
+	switch tmpl[i] {
+	case endFlag:
+		p.end = i+1
+	case argFlag:
+		//默认值与函数参数中可以包含嵌套的占位符，找到匹配的 }
+		depth := 0
+		for j := i+1; j < len(tmpl); j++ {
+			if tmpl[j]==startFlag {
+				depth++
+			}else if tmpl[j]==endFlag {
+				if depth==0 {
+					p.arg = tmpl[i+1:j]
+					p.hasArg = true
+					p.end = j+1
+					break
+				}
+				depth--
+			}
+		}
+		if !p.hasArg {
+			return p, false
+		}
+	default:
+		return p, false
+	}
+	p.raw = tmpl[start:p.end]
+	return p, true
+}
+
+func skipSpace(s string, i int) int{
+	for i < len(s) && (s[i]==' ' || s[i]=='\t') {
+		i++
+	}
+	return i
+}
+
+func isNameByte(b byte, first bool) bool{
+	if b>='a' && b<='z' || b>='A' && b<='Z' || b=='_' {
+		return true
+	}
+	if first {
+		return false
+	}
+	return b>='0' && b<='9' || b=='-' || b=='.'
+}
+
+// dateFunc {date:+1d|2006-01-02}
+func dateFunc(arg string) (string, error){
+	offset, layout := arg, "2006-01-02"
+	if idx := strings.IndexByte(arg, '|'); idx>=0 {
+		offset, layout = arg[:idx], arg[idx+1:]
 	}
-	return tmpl
+	t := time.Now()
+	offset = strings.TrimSpace(offset)
+	if strings.HasSuffix(offset, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(offset, "d"))
+		if err!=nil {
+			return "", fmt.Errorf("日期偏移格式错误: %s", offset)
+		}
+		t = t.AddDate(0, 0, days)
+	}else if offset!="" {
+		d, err := time.ParseDuration(offset)
+		if err!=nil {
+			return "", fmt.Errorf("日期偏移格式错误: %s", offset)
+		}
+		t = t.Add(d)
+	}
+	return t.Format(layout), nil
+}
+
+// nowFunc {now:unixms}
+func nowFunc(arg string) (string, error){
+	t := time.Now()
+	switch arg {
+	case "":
+		return t.Format("2006-01-02 15:04:05"), nil
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unixms":
+		return strconv.FormatInt(t.UnixNano() / int64(time.Millisecond), 10), nil
+	}
+	return t.Format(arg), nil
+}
+
+// urlencodeFunc {urlencode:x}
+func urlencodeFunc(arg string) (string, error){
+	return url.QueryEscape(arg), nil
 }
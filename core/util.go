@@ -1,5 +1,11 @@
 package core
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // If 模拟的三元运算符
 //   condition: 条件表达式
 //   trueVal: 表达式为true时返回的值
@@ -11,4 +17,34 @@ func If(condition bool, trueVal, falseVal interface{}) interface{} {
 		return trueVal
 	}
 	return falseVal
-}
\ No newline at end of file
+}
+
+var sizeUnits = []struct{
+	suffix string
+	bytes int64
+}{
+	{"GB", 1 << 30}, {"G", 1 << 30},
+	{"MB", 1 << 20}, {"M", 1 << 20},
+	{"KB", 1 << 10}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize 解析文件大小，如 10MB、512KB、1G，单位不区分大小写，没有单位时为字节数
+//   size: 文件大小
+// return: 字节数
+func ParseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			unit = u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err!=nil || n<0 {
+		return 0, fmt.Errorf("无法解析的文件大小: %s，应使用如 10MB、512KB 的格式", size)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"github.com/abeir/desktop-app/cmd"
 	"github.com/abeir/desktop-app/core/sys"
 	"github.com/abeir/desktop-app/restful"
+	"os"
 )
 
 func openBrowser(serv *restful.Server){
@@ -10,5 +13,13 @@ func openBrowser(serv *restful.Server){
 }
 
 func main() {
+	if handled, err := cmd.Run(os.Args[1:]); handled {
+		if err!=nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	restful.Init()
 	restful.NewServer().ServerStartedListener(openBrowser).Start()
 }
@@ -2,12 +2,14 @@ package restful
 
 import (
 	"context"
+	"fmt"
 	"github.com/abeir/desktop-app/core"
 	"github.com/abeir/desktop-app/core/log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -46,6 +48,12 @@ type Server struct {
 	//服务启动后调用的监听程序
 	serverStartedListener ServerStartedListener
 
+	lock sync.Mutex
+	//当前监听端口的http服务，设置向导修改端口后替换
+	serv *http.Server
+	//取消serv中所有请求的context
+	cancel context.CancelFunc
+
 	// 端口号
 	Port string
 	// 服务状态
@@ -95,15 +103,79 @@ func (s *Server) testPort() bool{
 // Start 启动服务
 func (s *Server) Start(){
 	s.State = ServerStarting
-	app := Gobal.Application
-	engine := Gobal.engine
+	app := Gobal.Application()
 
 	s.Port = app.Server.Port
-	serv := &http.Server{Addr:":" + s.Port, Handler: engine}
+	serv, cancel := newHttpServer(s.Port, Gobal.handler)
+	s.serv = serv
+	s.cancel = cancel
+	Gobal.setServer(s)
 
 	s.startServer(serv)
 	s.listenServerStarted()
-	s.gracefulShutdown(serv)
+	s.gracefulShutdown()
+}
+
+// newHttpServer 创建监听port的http服务，返回的cancel取消所有请求的context
+func newHttpServer(port string, handler http.Handler) (*http.Server, context.CancelFunc){
+	// 所有请求的context都来自baseCtx，关闭服务超时后取消，仍未完成的请求与其中对外的请求随之中断
+	baseCtx, cancel := context.WithCancel(context.Background())
+	serv := &http.Server{
+		Addr: ":" + port,
+		Handler: handler,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+	return serv, cancel
+}
+
+// shutdownServer 关闭服务，等待正在处理的请求完成，超过timeout仍未完成时取消请求的context
+func shutdownServer(serv *http.Server, cancel context.CancelFunc, timeout time.Duration) error{
+	ctx, done := context.WithTimeout(context.Background(), timeout)
+	defer done()
+	err := serv.Shutdown(ctx)
+	cancel()
+	return err
+}
+
+// currentPort 当前监听的端口
+func (s *Server) currentPort() string{
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Port
+}
+
+// Rebind 在新的端口上启动服务，成功后关闭原端口上的服务，原端口上正在处理的请求处理完成后才关闭连接
+// 新的端口无法监听时返回错误，继续使用原端口
+func (s *Server) Rebind(port string) error{
+	listener, err := net.Listen("tcp", ":" + port)
+	if err!=nil {
+		return fmt.Errorf("无法监听端口 %s: %w", port, err)
+	}
+	s.lock.Lock()
+	handler := s.serv.Handler
+	s.lock.Unlock()
+	serv, cancel := newHttpServer(port, handler)
+	go func(){
+		if err := serv.Serve(listener); err!=nil && err!=http.ErrServerClosed {
+			log.Errorf("listen: %s", err)
+		}
+	}()
+	s.lock.Lock()
+	old, oldCancel := s.serv, s.cancel
+	s.serv = serv
+	s.cancel = cancel
+	s.Port = port
+	s.lock.Unlock()
+	//在其他goroutine中关闭，调用Rebind的请求返回后原端口的服务才能关闭
+	go func(){
+		if err := shutdownServer(old, oldCancel, 5*time.Second); err!=nil {
+			log.Warnf("关闭原端口的服务失败: %s", err)
+		}
+	}()
+	log.Infof("服务已切换到端口 %s", port)
+	return nil
 }
 
 func (s *Server) startServer(serv *http.Server){
@@ -117,7 +189,7 @@ func (s *Server) startServer(serv *http.Server){
 }
 
 // gracefulShutdown 优雅关闭服务
-func (s *Server) gracefulShutdown(serv *http.Server){
+func (s *Server) gracefulShutdown(){
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal, 1)
@@ -127,12 +199,12 @@ func (s *Server) gracefulShutdown(serv *http.Server){
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutdown Server ...")
+	core.CloseQuietly(Gobal.watcher)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer func(){
-		cancel()
-	}()
-	if err := serv.Shutdown(ctx); err != nil {
+	s.lock.Lock()
+	serv, cancel := s.serv, s.cancel
+	s.lock.Unlock()
+	if err := shutdownServer(serv, cancel, 5*time.Second); err != nil {
 		log.Fatal("Server Shutdown: ", err)
 	}
 	log.Println("Server exiting")
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ConfigProvider 获取当前生效的应用配置与api配置
+type ConfigProvider func() (*config.ApplicationConfig, *config.ApiConfig)
+
+var configProvider ConfigProvider
+
+// SetConfigProvider 设置获取当前配置的方法，配置热加载后仍能获取到最新的配置
+func SetConfigProvider(provider ConfigProvider){
+	configProvider = provider
+}
+
+func NewAdminController(provider ConfigProvider) *AdminController {
+	return &AdminController{provider: provider}
+}
+
+// AdminController 用于排查问题的管理接口
+type AdminController struct {
+	provider ConfigProvider
+}
+
+// Config 返回合并各层配置后实际生效的配置，以及每个配置值的来源，敏感信息已遮盖
+func (a *AdminController) Config(ct *gin.Context){
+	if a.provider==nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage("配置尚未加载"))
+		return
+	}
+	app, api := a.provider()
+	rs := model.SuccessResultMessage("success").SetData(config.NewEffectiveConfig(app, api))
+	ct.JSON(http.StatusOK, rs)
+}
+
+// LogLevels 返回全局与各模块的日志级别
+func (a *AdminController) LogLevels(ct *gin.Context){
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(log.GetLevels()))
+}
+
+// SetLogLevels 在运行时修改全局与模块的日志级别，立即生效，重启后恢复为配置文件中的级别
+// 请求内容如 {"level":"info","modules":{"net":"debug","service":""}}，模块级别为空时取消单独设置的级别
+func (a *AdminController) SetLogLevels(ct *gin.Context){
+	levels := log.Levels{}
+	if err := ct.ShouldBindJSON(&levels); err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	if err := log.UpdateLevels(levels.Level, levels.Modules); err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	RequestLog(ct).WithFields(log.Fields{"level": levels.Level, "modules": levels.Modules}).Info("日志级别已修改")
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(log.GetLevels()))
+}
+
+// LogsPage 实时查看日志的页面
+func (a *AdminController) LogsPage(ct *gin.Context){
+	ct.HTML(http.StatusOK, "logs.html", nil)
+}
+
+// Logs 返回内存中最近的日志，可使用查询参数过滤：
+//	level: 最低级别
+//	module: 模块，包括子模块
+//	text: 消息或字段中包含的内容
+//	since、until: 时间范围，RFC3339格式的时间，或如 10m 表示10分钟之前
+//	limit: 最多返回最近的条数
+func (a *AdminController) Logs(ct *gin.Context){
+	filter, err := logFilter(ct)
+	if err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(log.Recent(filter)))
+}
+
+// 没有新日志时发送注释保持连接的间隔
+const streamHeartbeat = 15 * time.Second
+
+// StreamLogs 以Server-Sent Events推送新的日志，过滤条件与Logs相同，
+// 每条日志为一个 log 事件，id为日志的序号，断线重连时根据 Last-Event-ID 补发期间的日志
+func (a *AdminController) StreamLogs(ct *gin.Context){
+	filter, err := logFilter(ct)
+	if err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	filter.Limit = 0
+	//先订阅再读取补发的日志，避免遗漏两者之间的日志
+	sub := log.Subscribe(filter)
+	defer sub.Close()
+	var backlog []log.Record
+	if lastId := ct.GetHeader("Last-Event-ID"); lastId!="" {
+		if after, err := strconv.ParseUint(lastId, 10, 64); err==nil {
+			filter.After = after
+			backlog = log.Recent(filter)
+		}
+	}
+
+	header := ct.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	ct.Status(http.StatusOK)
+	var last uint64
+	for _, record := range backlog {
+		if err = writeEvent(ct.Writer, record); err!=nil {
+			return
+		}
+		last = record.Seq
+	}
+	ct.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ct.Request.Context().Done():
+			return
+		case record, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if record.Seq<=last {
+				continue
+			}
+			err = writeEvent(ct.Writer, record)
+		case <-heartbeat.C:
+			_, err = io.WriteString(ct.Writer, ": ping\n\n")
+		}
+		if err!=nil {
+			return
+		}
+		ct.Writer.Flush()
+	}
+}
+
+// writeEvent 写入一条日志事件
+func writeEvent(w io.Writer, record log.Record) error{
+	data, err := json.Marshal(record)
+	if err!=nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", record.Seq, data)
+	return err
+}
+
+// logFilter 从查询参数中获取日志的过滤条件
+func logFilter(ct *gin.Context) (log.Filter, error){
+	filter := log.Filter{
+		Level: ct.Query("level"),
+		Module: ct.Query("module"),
+		Text: ct.Query("text"),
+	}
+	var err error
+	if filter.Since, err = queryTime(ct, "since"); err!=nil {
+		return filter, err
+	}
+	if filter.Until, err = queryTime(ct, "until"); err!=nil {
+		return filter, err
+	}
+	if limit := ct.Query("limit"); limit!="" {
+		if filter.Limit, err = strconv.Atoi(limit); err!=nil {
+			return filter, fmt.Errorf("limit必须为数字: %s", limit)
+		}
+	}
+	return filter, filter.Validate()
+}
+
+// queryTime 解析RFC3339格式的时间，或如 10m 表示的之前的时间
+func queryTime(ct *gin.Context, key string) (time.Time, error){
+	value := ct.Query(key)
+	if value=="" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err==nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err!=nil {
+		return t, fmt.Errorf("%s的格式错误，应为RFC3339格式的时间或如10m的时间间隔: %s", key, value)
+	}
+	return t, nil
+}
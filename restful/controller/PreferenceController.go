@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/core/preference"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+)
+
+var preferenceStore *preference.Store
+
+// SetPreferenceStore 设置偏好设置存储
+func SetPreferenceStore(store *preference.Store){
+	preferenceStore = store
+}
+
+func NewPreferenceController(store *preference.Store) *PreferenceController {
+	return &PreferenceController{store: store}
+}
+
+// PreferenceController 用户偏好设置
+type PreferenceController struct {
+	store *preference.Store
+}
+
+// Get 获取当前的偏好设置
+func (p *PreferenceController) Get(ct *gin.Context){
+	if p.store==nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage("偏好设置尚未加载"))
+		return
+	}
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(p.store.Get()))
+}
+
+// Put 替换全部偏好设置，未提交的项恢复为默认值
+func (p *PreferenceController) Put(ct *gin.Context){
+	p.update(ct, p.store.Replace)
+}
+
+// Patch 修改部分偏好设置，未提交的项保持不变
+func (p *PreferenceController) Patch(ct *gin.Context){
+	p.update(ct, p.store.Patch)
+}
+
+func (p *PreferenceController) update(ct *gin.Context, apply func(data []byte) error){
+	if p.store==nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage("偏好设置尚未加载"))
+		return
+	}
+	data, err := ioutil.ReadAll(ct.Request.Body)
+	if err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	if err = apply(data); err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(p.store.Get()))
+}
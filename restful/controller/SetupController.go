@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"errors"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// SetupCompleteFunc 设置向导写入配置文件后调用，用于加载配置并切换到正常模式
+// 返回错误时写入的配置文件会被删除，用户可以修改后重新提交
+type SetupCompleteFunc func() error
+
+// SetupForm 设置向导提交的内容
+type SetupForm struct {
+	Profile string 	`form:"profile" json:"profile"`
+	Port string 	`form:"port" json:"port"`
+	Level string 	`form:"level" json:"level"`
+	LogPath string 	`form:"logPath" json:"logPath"`
+}
+
+// SetupRouter 设置模式下的路由，除设置向导与静态资源外的页面均跳转到设置向导
+func SetupRouter(engine *gin.Engine, setupController *SetupController){
+	//设置向导会写入配置文件，只允许从本机访问
+	local := LocalOnly()
+	engine.GET("/setup", local, setupController.Index)
+	engine.POST("/setup", local, setupController.Save)
+	engine.NoRoute(local, func(ct *gin.Context) {
+		if ct.Request.Method==http.MethodGet {
+			ct.Redirect(http.StatusFound, "/setup")
+			return
+		}
+		ct.JSON(http.StatusServiceUnavailable, model.FailedResultMessage("请先完成设置"))
+	})
+}
+
+// NewSetupController 创建设置向导
+//    defaults: 使用内置默认值加载的配置，用于填充表单
+//    onComplete: 配置文件写入后的回调
+func NewSetupController(defaults *config.ApplicationConfig, onComplete SetupCompleteFunc) *SetupController {
+	return &SetupController{defaults: defaults, onComplete: onComplete}
+}
+
+// SetupController 首次运行时没有配置文件，通过设置向导生成配置文件
+type SetupController struct {
+	defaults *config.ApplicationConfig
+	onComplete SetupCompleteFunc
+}
+
+func (s *SetupController) Index(ct *gin.Context){
+	ct.HTML(http.StatusOK, "setup.html", gin.H{
+		"file": config.DefaultApplicationFile(),
+		"form": SetupForm{
+			Profile: s.defaults.Environment,
+			Port: s.defaults.Server.Port,
+			Level: s.defaults.Logger.Level,
+			LogPath: s.defaults.Logger.Path,
+		},
+	})
+}
+
+// Save 保存设置向导生成的配置文件，配置有误时返回所有的错误
+func (s *SetupController) Save(ct *gin.Context){
+	form := SetupForm{}
+	if err := ct.ShouldBind(&form); err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	file := config.DefaultApplicationFile()
+	if core.IsExists(file) {
+		ct.JSON(http.StatusOK, model.FailedResultMessage("配置文件已存在: " + file))
+		return
+	}
+	if err := config.SaveConfigContent(file, form.content()); err!=nil {
+		ct.JSON(http.StatusOK, model.FailedResultMessage("保存配置文件失败: " + err.Error()))
+		return
+	}
+	if err := s.onComplete(); err!=nil {
+		_ = os.Remove(file)
+		ct.JSON(http.StatusOK, model.FailedResultMessage("配置有误").SetData(errorMessages(err)))
+		return
+	}
+	//端口修改后服务已切换到新的端口，页面需要跳转到新的端口
+	port := strings.TrimSpace(form.Port)
+	if port=="" {
+		port = s.defaults.Server.Port
+	}
+	rs := model.SuccessResultMessage("设置完成").SetData(gin.H{
+		"file": file,
+		"port": port,
+	})
+	ct.JSON(http.StatusOK, rs)
+}
+
+// content 生成配置文件内容，表单中的值写入defaults，所选环境只包含环境名
+func (f *SetupForm) content() *config.ConfigContent{
+	profile := strings.TrimSpace(f.Profile)
+	if profile=="" {
+		profile = config.DefaultProfile
+	}
+	return &config.ConfigContent{
+		Environment: profile,
+		Defaults: config.EnvironmentConfig{
+			Server: config.Server{Port: strings.TrimSpace(f.Port)},
+			Logger: config.Logger{
+				Level: strings.TrimSpace(f.Level),
+				Path: strings.TrimSpace(f.LogPath),
+			},
+		},
+		Configurations: []config.EnvironmentConfig{{Profile: profile}},
+	}
+}
+
+func errorMessages(err error) []string{
+	var v *config.ValidationError
+	if !errors.As(err, &v) {
+		return []string{err.Error()}
+	}
+	messages := make([]string, 0, len(v.Errors))
+	for _, e := range v.Errors {
+		messages = append(messages, e.Error())
+	}
+	return messages
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/abeir/desktop-app/restful/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func NewStationController() *StationController {
+	return &StationController{}
+}
+
+// StationController 车站信息
+type StationController struct {
+
+}
+
+// Names 获取车站名称，页面请求中断或服务关闭时同时中断对外的请求
+func (s *StationController) Names(ct *gin.Context){
+	names, err := service.NewStationService().Names(ct.Request.Context())
+	if err!=nil {
+		RequestLog(ct).Warnf("获取车站名称失败: %s", err)
+		ct.JSON(http.StatusOK, model.FailedResultMessage(err.Error()))
+		return
+	}
+	ct.JSON(http.StatusOK, model.SuccessResultMessage("success").SetData(names))
+}
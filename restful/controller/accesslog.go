@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger 为每个请求创建附带请求id的日志并保存在请求的context中，请求结束后按 server.accessLog 记录请求信息
+// 处理请求时可通过 RequestLog 获取该日志，配置热加载后立即使用新的配置
+func Logger() gin.HandlerFunc{
+	return AccessLogger(func() config.AccessLog {
+		if configProvider!=nil {
+			if app, _ := configProvider(); app!=nil {
+				return app.Server.AccessLog
+			}
+		}
+		return config.DefaultEnvironmentConfig().Server.AccessLog
+	})
+}
+
+// AccessLogger 按provider提供的配置记录请求日志
+//    provider: 获取当前的请求日志配置，每个请求都会调用
+func AccessLogger(provider func() config.AccessLog) gin.HandlerFunc{
+	options := &accessLogCache{}
+	return func(c *gin.Context) {
+		// 开始时间
+		startTime := time.Now()
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId=="" {
+			requestId = newRequestId()
+		}
+		c.Header(RequestIdHeader, requestId)
+		entry := log.Module("restful").WithField("requestId", requestId)
+		c.Request = c.Request.WithContext(log.NewContext(c.Request.Context(), entry))
+
+		opts := options.get(provider())
+		if opts.skip(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		var reqBody *limitedBuffer
+		var respBody *captureWriter
+		if opts.bodySize>0 && entry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+			if c.Request.Body!=nil {
+				reqBody = &limitedBuffer{limit: opts.bodySize}
+				c.Request.Body = &captureReader{ReadCloser: c.Request.Body, buf: reqBody}
+			}
+			respBody = &captureWriter{ResponseWriter: c.Writer, buf: &limitedBuffer{limit: opts.bodySize}}
+			c.Writer = respBody
+		}
+		// 处理请求
+		c.Next()
+
+		latency := time.Since(startTime)
+		opts.write(c, entry, latency)
+		if respBody!=nil {
+			fields := log.Fields{"responseBody": respBody.buf.String()}
+			if reqBody!=nil {
+				fields["requestBody"] = reqBody.String()
+			}
+			entry.WithFields(fields).Debug("request body")
+		}
+	}
+}
+
+// accessLogCache 解析后的请求日志配置，配置修改后重新解析
+type accessLogCache struct {
+	lock sync.Mutex
+	config config.AccessLog
+	options *accessLogOptions
+}
+
+func (a *accessLogCache) get(cfg config.AccessLog) *accessLogOptions{
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.options==nil || a.config!=cfg {
+		a.config = cfg
+		a.options = newAccessLogOptions(cfg)
+	}
+	return a.options
+}
+
+// accessLogOptions 解析后的请求日志配置，配置有误的项使用默认行为
+type accessLogOptions struct {
+	format string
+	skipPatterns []string
+	slowThreshold time.Duration
+	bodySize int64
+}
+
+func newAccessLogOptions(cfg config.AccessLog) *accessLogOptions{
+	opts := &accessLogOptions{format: strings.TrimSpace(cfg.Format), skipPatterns: cfg.SkipPatterns()}
+	if opts.format=="" {
+		opts.format = config.AccessLogFields
+	}
+	if cfg.SlowThreshold!="" {
+		opts.slowThreshold, _ = time.ParseDuration(cfg.SlowThreshold)
+	}
+	if cfg.BodySize!="" {
+		opts.bodySize, _ = core.ParseSize(cfg.BodySize)
+	}
+	return opts
+}
+
+// skip 路径是否不需要记录，以 /* 结尾的规则包括所有子路径
+func (o *accessLogOptions) skip(p string) bool{
+	for _, pattern := range o.skipPatterns {
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(p, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// write 记录请求日志，处理时间超过阈值时以Warn级别记录
+func (o *accessLogOptions) write(c *gin.Context, entry *log.Entry, latency time.Duration){
+	slow := o.slowThreshold>0 && latency>=o.slowThreshold
+	var message string
+	switch o.format {
+	case config.AccessLogFields:
+		entry = entry.WithFields(log.Fields{
+			"status": c.Writer.Status(),
+			"latencyMs": latency.Milliseconds(),
+			"clientIp": c.ClientIP(),
+			"method": c.Request.Method,
+			"uri": c.Request.RequestURI,
+		})
+		message = "request"
+	case config.AccessLogCombined:
+		message = combinedLine(c)
+	default:
+		message, _ = core.NewTemplate().Render(o.format, accessLogArgs(c, latency))
+	}
+	if slow {
+		entry.WithFields(log.Fields{"slow": true, "latencyMs": latency.Milliseconds()}).Warn(message)
+		return
+	}
+	entry.Info(message)
+}
+
+// accessLogArgs 自定义模板中可以使用的参数
+func accessLogArgs(c *gin.Context, latency time.Duration) map[string]string{
+	return map[string]string{
+		"method": c.Request.Method,
+		"uri": c.Request.RequestURI,
+		"path": c.Request.URL.Path,
+		"status": strconv.Itoa(c.Writer.Status()),
+		"latencyMs": strconv.FormatInt(latency.Milliseconds(), 10),
+		"clientIp": c.ClientIP(),
+		"requestId": c.Writer.Header().Get(RequestIdHeader),
+		"size": strconv.Itoa(responseSize(c)),
+		"userAgent": c.Request.UserAgent(),
+		"referer": c.Request.Referer(),
+		"protocol": c.Request.Proto,
+		"time": time.Now().Format(time.RFC3339),
+	}
+}
+
+// combinedLine Apache combined格式的请求日志
+func combinedLine(c *gin.Context) string{
+	size := "-"
+	if n := responseSize(c); n>0 {
+		size = strconv.Itoa(n)
+	}
+	return fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %s "%s" "%s"`,
+		c.ClientIP(), time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+		c.Request.Method, c.Request.RequestURI, c.Request.Proto,
+		c.Writer.Status(), size, c.Request.Referer(), c.Request.UserAgent())
+}
+
+// responseSize 响应内容的大小，未写入内容时为0
+func responseSize(c *gin.Context) int{
+	if size := c.Writer.Size(); size>0 {
+		return size
+	}
+	return 0
+}
+
+// limitedBuffer 只保存前limit个字节的内容
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int64
+	truncated bool
+}
+
+func (b *limitedBuffer) capture(p []byte){
+	remain := b.limit - int64(b.Len())
+	if remain<=0 {
+		b.truncated = b.truncated || len(p)>0
+		return
+	}
+	if int64(len(p))>remain {
+		p = p[:remain]
+		b.truncated = true
+	}
+	b.Write(p)
+}
+
+func (b *limitedBuffer) String() string{
+	if b.truncated {
+		return b.Buffer.String() + "...(truncated)"
+	}
+	return b.Buffer.String()
+}
+
+// captureReader 读取请求内容时保存前面的部分
+type captureReader struct {
+	io.ReadCloser
+	buf *limitedBuffer
+}
+
+func (r *captureReader) Read(p []byte) (int, error){
+	n, err := r.ReadCloser.Read(p)
+	r.buf.capture(p[:n])
+	return n, err
+}
+
+// captureWriter 写入响应内容时保存前面的部分
+type captureWriter struct {
+	gin.ResponseWriter
+	buf *limitedBuffer
+}
+
+func (w *captureWriter) Write(p []byte) (int, error){
+	w.buf.capture(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error){
+	w.buf.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -1,9 +1,12 @@
 package controller
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/abeir/desktop-app/core/config"
 	"github.com/abeir/desktop-app/core/log"
 	"github.com/gin-gonic/gin"
+	"strconv"
 	"time"
 )
 
@@ -17,39 +20,49 @@ func Router(engine *gin.Engine){
 		test.GET("/", testController.Index)
 		test.GET("/hello", testController.Hello)
 	}
+
+	//管理接口没有身份认证，只允许从本机访问
+	admin := engine.Group("/admin", LocalOnly())
+	{
+		adminController := NewAdminController(configProvider)
+		admin.GET("/config", adminController.Config)
+		admin.GET("/log/levels", adminController.LogLevels)
+		admin.PUT("/log/levels", adminController.SetLogLevels)
+		admin.GET("/logs", adminController.Logs)
+		admin.GET("/logs/view", adminController.LogsPage)
+		admin.GET("/logs/stream", adminController.StreamLogs)
+	}
+
+	api := engine.Group("/api")
+	{
+		preferenceController := NewPreferenceController(preferenceStore)
+		api.GET("/preferences", preferenceController.Get)
+		api.PUT("/preferences", preferenceController.Put)
+		api.PATCH("/preferences", preferenceController.Patch)
+
+		stationController := NewStationController()
+		api.GET("/stations", stationController.Names)
+	}
 }
 
 func Validator(){
 
 }
 
-func Logger() gin.HandlerFunc{
-	return func(c *gin.Context) {
-		// 开始时间
-		startTime := time.Now()
-		// 处理请求
-		c.Next()
-		// 结束时间
-		endTime := time.Now()
-		// 执行时间
-		latencyTime := endTime.Sub(startTime)
-		// 请求方式
-		reqMethod := c.Request.Method
-		// 请求路由
-		reqUri := c.Request.RequestURI
-		// 状态码
-		statusCode := c.Writer.Status()
-		// 请求IP
-		clientIP := c.ClientIP()
-		// 日志格式
-		log.Infof("| %3d | %13v | %15s | %s | %s",
-			statusCode,
-			latencyTime,
-			clientIP,
-			reqMethod,
-			reqUri,
-		)
+// RequestIdHeader 请求id所在的请求头，请求中没有时自动生成并在响应头中返回
+const RequestIdHeader = "X-Request-Id"
+
+// RequestLog 获取当前请求的日志，日志中附带请求id
+func RequestLog(c *gin.Context) *log.Entry{
+	return log.FromContext(c.Request.Context())
+}
+
+func newRequestId() string{
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err!=nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
 	}
+	return hex.EncodeToString(id)
 }
 
 func SetMode(config *config.ApplicationConfig){
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// LocalOnly 只允许从本机访问，用于没有身份认证的管理接口与设置向导
+// 请求的来源地址与Host请求头都必须是本机，Host不是本机时拒绝，防止网页通过DNS重绑定访问
+func LocalOnly() gin.HandlerFunc{
+	return func(ct *gin.Context) {
+		if !isLoopbackAddr(ct.Request.RemoteAddr) || !isLocalHost(ct.Request.Host) {
+			RequestLog(ct).Warnf("拒绝非本机的请求: %s, Host: %s", ct.Request.RemoteAddr, ct.Request.Host)
+			ct.AbortWithStatusJSON(http.StatusForbidden, model.FailedResultMessage("只允许从本机访问"))
+			return
+		}
+		ct.Next()
+	}
+}
+
+// isLoopbackAddr 请求的来源地址是否为本机，不使用X-Forwarded-For等可以伪造的请求头
+func isLoopbackAddr(remoteAddr string) bool{
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err!=nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip!=nil && ip.IsLoopback()
+}
+
+// isLocalHost Host请求头是否为 localhost 或本机的ip
+func isLocalHost(hostHeader string) bool{
+	host, _, err := net.SplitHostPort(hostHeader)
+	if err!=nil {
+		host = strings.Trim(hostHeader, "[]")
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip!=nil && ip.IsLoopback()
+}
@@ -0,0 +1,30 @@
+package restful
+
+import (
+	"net/http"
+	"sync"
+)
+
+// switchHandler 可在运行时替换的http.Handler，设置向导完成后由设置模式切换到正常模式时无需重启服务
+type switchHandler struct {
+	lock sync.RWMutex
+	handler http.Handler
+}
+
+func (s *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request){
+	s.lock.RLock()
+	handler := s.handler
+	s.lock.RUnlock()
+	if handler==nil {
+		http.Error(w, "服务正在启动", http.StatusServiceUnavailable)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// Switch 替换处理请求的handler，正在处理的请求不受影响
+func (s *switchHandler) Switch(handler http.Handler){
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handler = handler
+}
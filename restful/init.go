@@ -1,64 +1,304 @@
 package restful
 
 import (
+	"errors"
 	"github.com/abeir/desktop-app/core/config"
 	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/abeir/desktop-app/core/preference"
 	"github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
 )
 
 var Gobal *gobalContent
 
 type gobalContent struct {
-	Application config.ApplicationConfig
-	Api config.ApiConfig
+	lock sync.RWMutex
+	application *config.ApplicationConfig
+	api *config.ApiConfig
 
 	engine *gin.Engine
+	handler *switchHandler
+	Preference *preference.Store
+	watcher *config.Watcher
+	// 是否处于首次运行的设置模式
+	setup bool
+	// 设置向导修改端口时用于切换监听的端口
+	server *Server
 }
 
 
-func init(){
-	Gobal = &gobalContent{}
+// Application 当前生效的应用配置，配置重新加载后返回新的实例，返回的实例不会再被修改，可以在任意goroutine中使用
+func (g *gobalContent) Application() *config.ApplicationConfig{
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.application
+}
 
-	initApplicationConfig()
-	initApiConfig()
-	initLog(&Gobal.Application)
-	initController(&Gobal.Application)
+// Api 当前生效的api配置，配置重新加载后返回新的实例，返回的实例不会再被修改，可以在任意goroutine中使用
+func (g *gobalContent) Api() *config.ApiConfig{
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.api
 }
 
-func initApplicationConfig(){
-	applicationConfig := config.NewApplicationConfig()
-	if err := applicationConfig.Load(); err!=nil {
+// Setup 是否处于首次运行的设置模式
+func (g *gobalContent) Setup() bool{
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.setup
+}
+
+func (g *gobalContent) setServer(server *Server){
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.server = server
+}
+
+func (g *gobalContent) getServer() *Server{
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.server
+}
+
+// setApplication 替换应用配置，app在替换后不能再修改
+func (g *gobalContent) setApplication(app *config.ApplicationConfig){
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.application = app
+}
+
+// setApi 替换api配置，api在替换后不能再修改
+func (g *gobalContent) setApi(api *config.ApiConfig){
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.api = api
+}
+
+// Init 加载配置、初始化日志与路由，需要在 NewServer 之前调用
+// 找不到配置文件时进入设置模式，只提供设置向导页面，设置完成后切换到正常模式
+func Init(){
+	Gobal = &gobalContent{handler: &switchHandler{}, api: config.NewApiConfig()}
+
+	initDirs()
+	//只在启动时将旧版本的配置文件升级后写回，热加载时只在内存中升级
+	applicationConfig := config.NewApplicationConfig().SetRewriteMigrated(true)
+	err := applicationConfig.Load()
+	if errors.Is(err, config.ErrConfigNotFound) {
+		initSetup()
+		return
+	}
+	if err!=nil {
+		panic(err)
+	}
+	Gobal.setApplication(applicationConfig)
+	apiConfig := initApiConfig()
+	initLog(applicationConfig)
+	logWarnings(applicationConfig)
+	logMigrations(applicationConfig.File(), applicationConfig.Migrations())
+	initPreference()
+	initController(applicationConfig)
+	initConfigWatcher(applicationConfig, apiConfig)
+}
+
+// initDirs 首次运行时创建用户配置、数据、缓存与日志目录
+func initDirs(){
+	if err := paths.EnsureDirs(); err!=nil {
 		panic(err)
 	}
-	Gobal.Application = *applicationConfig
 }
 
-func initApiConfig(){
-	apiConfig := config.NewApiConfig()
+func initApiConfig() *config.ApiConfig{
+	apiConfig := config.NewApiConfig().SetProfile(Gobal.Application().Environment)
 	if err := apiConfig.Load(); err!=nil {
 		panic(err)
 	}
-	Gobal.Api = *apiConfig
+	Gobal.setApi(apiConfig)
+	return apiConfig
 }
 
-func initLog(app *config.ApplicationConfig){
-	log.InitLog(app)
+// initSetup 进入设置模式，使用内置默认值启动服务并提供设置向导
+func initSetup(){
+	defaults := config.NewApplicationConfig()
+	if err := defaults.LoadDefaults(); err!=nil {
+		panic(err)
+	}
+	Gobal.lock.Lock()
+	Gobal.application = defaults
+	Gobal.setup = true
+	Gobal.lock.Unlock()
+	initLog(defaults)
+	log.Warnf("未找到配置文件，进入设置模式，请在浏览器中完成设置: %s", config.DefaultApplicationFile())
+
+	engine := newEngine()
+	controller.SetupRouter(engine, controller.NewSetupController(defaults, completeSetup))
+	Gobal.engine = engine
+	Gobal.handler.Switch(engine)
 }
 
-func initController(app *config.ApplicationConfig){
-	controller.SetMode(app)
+// 同时只能有一个设置向导的请求切换到正常模式
+var setupLock sync.Mutex
 
-	Gobal.engine = gin.New()
+// completeSetup 设置向导写入配置文件后，重新加载配置并切换到正常模式，端口修改时在新的端口上启动服务并关闭原端口
+func completeSetup() error{
+	setupLock.Lock()
+	defer setupLock.Unlock()
+	if !Gobal.Setup() {
+		return errors.New("已完成设置")
+	}
+	applicationConfig := config.NewApplicationConfig()
+	if err := applicationConfig.Load(); err!=nil {
+		return err
+	}
+	apiConfig := config.NewApiConfig().SetProfile(applicationConfig.Environment)
+	if err := apiConfig.Load(); err!=nil {
+		//设置向导无法修改api配置，api配置有误时不影响切换到正常模式
+		log.Warnf("加载api配置失败: %s", err)
+		apiConfig = config.NewApiConfig()
+	}
+	if err := log.InitLog(applicationConfig); err!=nil {
+		return err
+	}
+	logWarnings(applicationConfig)
+	if server := Gobal.getServer(); server!=nil && applicationConfig.Server.Port!=server.currentPort() {
+		if err := server.Rebind(applicationConfig.Server.Port); err!=nil {
+			//恢复设置模式的日志输出，用户可以修改端口后重新提交
+			initLog(Gobal.Application())
+			return err
+		}
+	}
+	Gobal.lock.Lock()
+	Gobal.application = applicationConfig
+	Gobal.api = apiConfig
+	Gobal.setup = false
+	Gobal.lock.Unlock()
+	initPreference()
+	initController(applicationConfig)
+	initConfigWatcher(applicationConfig, apiConfig)
+	log.Infof("设置完成，已切换到正常模式: %s", applicationConfig.File())
+	return nil
+}
+
+// loggerOutputChanged 日志目录或输出是否修改，只修改级别时无需重新初始化日志
+func loggerOutputChanged(old, new config.Logger) bool{
+	old.Level, old.Modules = new.Level, new.Modules
+	return !reflect.DeepEqual(old, new)
+}
+
+// updateLogLevels 修改全局与模块的日志级别
+func updateLogLevels(old, new config.Logger){
+	if old.Level != new.Level {
+		if err := log.SetLevel(new.Level); err!=nil {
+			log.Warnf("修改日志级别失败: %s, %s", new.Level, err)
+		}
+	}
+	if old.Modules != new.Modules {
+		levels, err := new.ModuleLevels()
+		if err==nil {
+			err = log.SetModuleLevels(levels)
+		}
+		if err!=nil {
+			log.Warnf("修改模块日志级别失败: %s, %s", new.Modules, err)
+		}
+	}
+}
 
-	engine := Gobal.engine
+// initConfigWatcher 监视配置文件，修改后无需重启即可生效，修改有误时保留原有配置
+func initConfigWatcher(app *config.ApplicationConfig, api *config.ApiConfig){
+	watcher := config.NewWatcher(app, api).
+		OnApplicationChange(func(event *config.ApplicationChangeEvent) {
+			Gobal.setApplication(event.Config)
+			if loggerOutputChanged(event.Old.Logger, event.New.Logger) {
+				if err := log.InitLog(event.Config); err!=nil {
+					log.Warnf("修改日志输出失败，继续使用原有输出: %s", err)
+				}
+			}else{
+				updateLogLevels(event.Old.Logger, event.New.Logger)
+			}
+			logWarnings(event.Config)
+			log.Infof("应用配置已重新加载: %s", event.Config.File())
+		}).
+		OnApiChange(func(event *config.ApiChangeEvent) {
+			Gobal.setApi(event.Config)
+			log.Infof("api配置已重新加载: %s", event.Config.File())
+		}).
+		OnError(func(file string, err error) {
+			log.Errorf("重新加载配置失败，继续使用原有配置: %s, %s", file, err)
+		})
+	if err := watcher.Start(); err!=nil {
+		log.Warnf("监视配置文件失败，修改配置后需要重启: %s", err)
+		return
+	}
+	Gobal.watcher = watcher
+}
+
+// initPreference 加载用户偏好设置，文件有误时使用默认值，修改后会覆盖有误的文件
+func initPreference(){
+	store := preference.NewStore(preference.DefaultFile())
+	if err := store.Load(); err!=nil {
+		log.Errorf("加载偏好设置失败，使用默认值且不能修改，修复或删除文件后重启: %s", err)
+	}
+	logMigrations(store.File(), store.Migrations())
+	store.OnChange(func(event *preference.ChangeEvent) {
+		log.Infof("偏好设置已修改: %s", store.File())
+	})
+	Gobal.Preference = store
+}
+
+// logWarnings 记录配置校验发现的警告
+func logWarnings(app *config.ApplicationConfig){
+	for _, w := range app.Warnings() {
+		log.Warnf("配置警告: %s", w)
+	}
+}
 
-	engine.LoadHTMLGlob("ui/template/**/*")
-	engine.StaticFS("assets", http.Dir("ui/assets"))
+// logMigrations 记录加载文件时执行的升级函数
+func logMigrations(file string, migrations []migrate.Migration){
+	for _, m := range migrations {
+		log.Infof("已升级文件 %s, %s", file, m)
+	}
+}
+
+// initLog 初始化日志，失败时日志只输出到控制台
+func initLog(app *config.ApplicationConfig){
+	if err := log.InitLog(app); err!=nil {
+		log.Errorf("初始化日志失败，日志只输出到控制台: %s", err)
+	}
+}
+
+// newEngine 创建加载了模板与静态资源的gin.Engine
+func newEngine() *gin.Engine{
+	engine := gin.New()
+
+	assetDir, err := paths.AssetDir()
+	if err!=nil {
+		panic(err)
+	}
 	engine.Use(gin.Recovery())
 	engine.Use(controller.Logger())
+	engine.LoadHTMLGlob(filepath.Join(assetDir, "template", "**", "*"))
+	engine.StaticFS("assets", http.Dir(filepath.Join(assetDir, "assets")))
+	return engine
+}
+
+func initController(app *config.ApplicationConfig){
+	controller.SetMode(app)
+
+	engine := newEngine()
 
 	controller.Validator()
+	controller.SetConfigProvider(func() (*config.ApplicationConfig, *config.ApiConfig) {
+		return Gobal.Application(), Gobal.Api()
+	})
+	controller.SetPreferenceStore(Gobal.Preference)
+	service.SetApiProvider(Gobal.Api)
 	controller.Router(engine)
+	Gobal.engine = engine
+	Gobal.handler.Switch(engine)
 }
@@ -1,6 +1,8 @@
 package service
 
-const stationNameId = ""
+import "context"
+
+const stationNameId = "station_name"
 
 func NewStationService() *StationService {
 	service := &StationService{
@@ -14,8 +16,11 @@ type StationService struct {
 	base *BaseService
 }
 
-func (s *StationService) names(){
-
+// Names 获取车站名称的脚本，ctx取消后中断请求
+func (s *StationService) Names(ctx context.Context) (string, error){
+	resp, err := s.base.ExecuteContext(ctx, stationNameId, nil)
+	if err!=nil {
+		return "", err
+	}
+	return resp.Text(), nil
 }
-
-
@@ -1,12 +1,54 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/abeir/desktop-app/core"
 	"github.com/abeir/desktop-app/core/config"
-	"github.com/abeir/desktop-app/restful"
+	"github.com/abeir/desktop-app/core/net"
+	"net/http"
+	"net/url"
 )
 
+// ApiProvider 获取当前生效的api配置
+type ApiProvider func() *config.ApiConfig
+
+var apiProvider ApiProvider
+
+// SetApiProvider 设置获取当前api配置的方法，配置热加载后仍能获取到最新的配置
+func SetApiProvider(provider ApiProvider){
+	apiProvider = provider
+}
+
+// ApiResponse 执行api得到的响应
+type ApiResponse struct {
+	StatusCode int
+	Headers map[string][]string
+	Body []byte
+	// 响应内容的类型，与api配置中的response一致
+	Type string
+}
+
+// Text 以字符串形式获取响应内容
+func (r *ApiResponse) Text() string{
+	return string(r.Body)
+}
+
+// Json 将json格式的响应内容解析到v中
+func (r *ApiResponse) Json(v interface{}) error{
+	return json.Unmarshal(r.Body, v)
+}
+
 type BaseService struct {
 	api config.Api
+	account string
+}
+
+// SetAccount 设置发送请求时使用的账号，同一账号的cookie会保存并在之后的请求中发送，为空时使用默认账号
+func (b *BaseService) SetAccount(account string){
+	b.account = account
 }
 
 // FindUrl 从api配置中，根据id获取url
@@ -17,16 +59,154 @@ func (b *BaseService) FindUrl(id string) string{
 	if !b.api.IsEmpty() {
 		return b.api.Url
 	}
-	apis := restful.Gobal.Api.Apis
-	for _, api := range apis {
+	if api, ok := findApi(id); ok {
+		b.api = api
+		return api.Url
+	}
+	return ""
+}
+
+// Execute 根据id执行api配置中定义的请求，请求失败、服务端错误(5xx)或网络繁忙时按配置的次数重试，
+// 重试规则与 net.RetryPolicy 相同，POST等非幂等的请求不重试
+// param
+//    id: api的id
+//    params: 模板参数，替换请求头、查询参数与请求体中的 {name}，同名时优先于url节点
+func (b *BaseService) Execute(id string, params map[string]string) (*ApiResponse, error){
+	return b.ExecuteContext(context.Background(), id, params)
+}
+
+// ExecuteContext 与 Execute 相同，ctx取消或超时后中断请求且不再重试，
+// 处理页面请求时传入页面请求的context，如 ct.Request.Context()，浏览器中断请求或服务关闭时同时中断对外的请求
+func (b *BaseService) ExecuteContext(ctx context.Context, id string, params map[string]string) (*ApiResponse, error){
+	api, ok := findApi(id)
+	if !ok {
+		return nil, fmt.Errorf("api不存在: %s", id)
+	}
+	args := templateArgs(params)
+	target, err := requestUrl(&api, args)
+	if err!=nil {
+		return nil, err
+	}
+	return b.request(ctx, &api, target, args)
+}
+
+// retryPolicy 按api配置的重试次数创建重试策略，服务端错误(5xx)也会重试
+func retryPolicy(api *config.Api) *net.RetryPolicy{
+	policy := net.DefaultRetryPolicy()
+	policy.MaxAttempts = api.Retries + 1
+	policy.RetryStatus = []int{http.StatusTooManyRequests}
+	for status := http.StatusInternalServerError; status<600; status++ {
+		policy.RetryStatus = append(policy.RetryStatus, status)
+	}
+	return policy
+}
+
+// request 发送请求，满足重试条件时由HttpClient按重试策略重试
+func (b *BaseService) request(ctx context.Context, api *config.Api, target string, args map[string]string) (*ApiResponse, error){
+	tmpl := core.NewTemplate().Strict(true)
+	client := net.NewHttpClient().SetMethod(net.HttpMethod(api.HttpMethod())).UseAccount(b.account).
+		SetRetryPolicy(retryPolicy(api))
+	if timeout := api.TimeoutDuration(); timeout>0 {
+		client.SetTimeout(timeout)
+	}
+	if api.ContentType!="" {
+		client.SetContentType(net.ContentType(api.ContentType))
+	}
+	for name, value := range api.Headers {
+		header, err := tmpl.Render(value, args)
+		if err!=nil {
+			return nil, fmt.Errorf("api的请求头%s解析失败: %s, %w", name, api.Id, err)
+		}
+		client.AddHeader(name, header)
+	}
+	if api.Body!="" {
+		body, err := tmpl.Render(api.Body, args)
+		if err!=nil {
+			return nil, fmt.Errorf("api的请求体解析失败: %s, %w", api.Id, err)
+		}
+		client.SetBody([]byte(body))
+	}
+	body, err := client.RequestContext(ctx, target)
+	if err!=nil {
+		return nil, err
+	}
+	if client.StatusCode() >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("服务端错误: %d %s", client.StatusCode(), target)
+	}
+	if api.ResponseType()==config.ResponseJson && !json.Valid(body) {
+		return nil, fmt.Errorf("响应内容不是有效的json: %s", target)
+	}
+	return &ApiResponse{
+		StatusCode: client.StatusCode(),
+		Headers: client.ResponseHeaders(),
+		Body: body,
+		Type: api.ResponseType(),
+	}, nil
+}
+
+func findApi(id string) (config.Api, bool){
+	if apiProvider==nil {
+		return config.Api{}, false
+	}
+	apiConfig := apiProvider()
+	if apiConfig==nil {
+		return config.Api{}, false
+	}
+	for _, api := range apiConfig.Apis {
 		if id == api.Id {
-			b.api = api
-			return api.Url
+			return api, true
 		}
 	}
-	return ""
+	return config.Api{}, false
 }
 
-func (b *BaseService) request(){
+// templateArgs 合并url节点与调用时提供的参数
+func templateArgs(params map[string]string) map[string]string{
+	args := make(map[string]string)
+	if apiProvider!=nil {
+		if apiConfig := apiProvider(); apiConfig!=nil {
+			for k, v := range apiConfig.Urls {
+				args[k] = v
+			}
+		}
+	}
+	for k, v := range params {
+		args[k] = v
+	}
+	return args
+}
 
+// requestUrl 将get与query合并到url的查询字符串中，存在未解析的参数时返回错误
+func requestUrl(api *config.Api, args map[string]string) (string, error){
+	tmpl := core.NewTemplate().Strict(true)
+	raw, err := tmpl.Render(api.Url, args)
+	if err!=nil {
+		return "", fmt.Errorf("api的url解析失败: %s, %w", api.Id, err)
+	}
+	u, err := url.Parse(raw)
+	if err!=nil {
+		return "", fmt.Errorf("api的url格式错误: %s, %w", api.Id, err)
+	}
+	if api.Get=="" && len(api.Query)==0 {
+		return u.String(), nil
+	}
+	query := u.Query()
+	get, err := url.ParseQuery(api.Get)
+	if err!=nil {
+		return "", errors.New("api的get格式错误: " + api.Id)
+	}
+	for k, values := range get {
+		for _, v := range values {
+			query.Add(k, v)
+		}
+	}
+	for k, v := range api.Query {
+		value, err := tmpl.Render(v, args)
+		if err!=nil {
+			return "", fmt.Errorf("api的查询参数%s解析失败: %s, %w", k, api.Id, err)
+		}
+		query.Set(k, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
@@ -0,0 +1,106 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const apidMainYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+  static: 'https://static.12306.cn'
+api:
+  - id: 'station_name'
+    url: '{u12306}/otn/resources/js/framework/station_name.js'
+`
+
+const apidQueryYml = `url:
+  u12306: 'http://127.0.0.1:9000'
+api:
+  - id: 'left_ticket'
+    url: '{u12306}/otn/leftTicket/query'
+`
+
+const apidLoginYml = `api:
+  - id: 'login'
+    url: '{static}/passport/web/login'
+`
+
+const apidDuplicateYml = `api:
+  - id: 'login'
+    url: '{u12306}/other/login'
+`
+
+// writeApid 写入api.yml与api.d目录中的文件
+func writeApid(f *configFixture) {
+	f.Api("api.yml", apidMainYml)
+	f.Write(filepath.Join(conf.ApiDir, "20-query.yml"), apidQueryYml)
+	f.Write(filepath.Join(conf.ApiDir, "10-login.yml"), apidLoginYml)
+	f.Write(filepath.Join(conf.ApiDir, "README.md"), "ignored")
+}
+
+func TestApiDirMerge(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	writeApid(f)
+	dir := f.Dir
+
+	api := conf.NewApiConfig()
+	if !assert.NoError(t, api.Load()) {
+		return
+	}
+	assert.Equal(t, []string{
+		filepath.Join(dir, "api.yml"),
+		filepath.Join(dir, conf.ApiDir, "10-login.yml"),
+		filepath.Join(dir, conf.ApiDir, "20-query.yml"),
+	}, api.Files())
+	assert.Equal(t, "http://127.0.0.1:9000", api.Urls["u12306"])
+
+	urls := make(map[string]string)
+	for _, a := range api.Apis {
+		urls[a.Id] = a.Url
+	}
+	assert.Equal(t, "http://127.0.0.1:9000/otn/resources/js/framework/station_name.js", urls["station_name"])
+	assert.Equal(t, "https://static.12306.cn/passport/web/login", urls["login"])
+	assert.Equal(t, "http://127.0.0.1:9000/otn/leftTicket/query", urls["left_ticket"])
+	assert.Equal(t, filepath.Join(dir, conf.ApiDir, "10-login.yml"), api.FileOf("login"))
+}
+
+func TestApiDirDuplicateId(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	writeApid(f)
+	f.Write(filepath.Join(conf.ApiDir, "30-duplicate.yml"), apidDuplicateYml)
+
+	err := conf.NewApiConfig().Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "10-login.yml")
+		assert.Contains(t, err.Error(), "30-duplicate.yml")
+	}
+}
+
+func TestApiDirWatch(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	writeApid(f)
+	app, _ := loadWatched(f)
+	f.Api("api.yml", apidMainYml)
+	api := f.LoadApi()
+
+	events := make(chan *conf.ApiChangeEvent, 1)
+	watcher := conf.NewWatcher(app, api).OnApiChange(func(event *conf.ApiChangeEvent) {
+		events <- event
+	})
+	assert.NoError(t, watcher.Start())
+	defer watcher.Close()
+
+	f.Write(filepath.Join(conf.ApiDir, "30-order.yml"), "api:\n  - id: 'order'\n    url: '{u12306}/order'\n")
+	select {
+	case event := <-events:
+		assert.Len(t, event.New, len(event.Old) + 1)
+	case <-time.After(time.Second * 3):
+		t.Fatal("api.d中新增文件后未收到变化事件")
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const apiDefYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+api:
+  - id: 'query_ticket'
+    url: '{u12306}/otn/leftTicket/query'
+    method: 'post'
+    headers:
+      Referer: '{u12306}/otn/leftTicket/init'
+    query:
+      date: '{date}'
+    body: '{"from":"{from}"}'
+    contentType: 'application/json'
+    timeout: '5s'
+    retries: 2
+    response: 'json'
+  - id: 'station_name'
+    url: '{u12306}/otn/resources/js/framework/station_name.js'
+`
+
+const apiDefInvalidYml = `api:
+  - id: 'bad'
+    url: 'http://127.0.0.1'
+    method: 'FETCH'
+    timeout: 'soon'
+    retries: -1
+    response: 'xml'
+`
+
+func TestApiDefinition(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.yml", apiDefYml)
+	api := f.LoadApi()
+
+	query := api.Apis[0]
+	assert.Equal(t, "POST", query.HttpMethod())
+	assert.Equal(t, "{u12306}/otn/leftTicket/init", query.Headers["Referer"])
+	assert.Equal(t, "{date}", query.Query["date"])
+	assert.Equal(t, "application/json", query.ContentType)
+	assert.Equal(t, time.Second * 5, query.TimeoutDuration())
+	assert.Equal(t, 2, query.Retries)
+	assert.Equal(t, conf.ResponseJson, query.ResponseType())
+
+	station := api.Apis[1]
+	assert.Equal(t, "GET", station.HttpMethod())
+	assert.Equal(t, time.Duration(0), station.TimeoutDuration())
+	assert.Equal(t, conf.ResponseText, station.ResponseType())
+}
+
+func TestApiDefinitionInvalid(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.yml", apiDefInvalidYml)
+
+	err := conf.NewApiConfig().Load()
+	v, ok := err.(*conf.ValidationError)
+	if !assert.True(t, ok, "应返回 *ValidationError: %v", err) {
+		return
+	}
+	var fields []string
+	for _, e := range v.Errors {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"api[0].method", "api[0].timeout", "api[0].retries", "api[0].response"}, fields)
+}
+
+const apiNestedUrlYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+  otn: '{u12306}/otn'
+api:
+  - id: 'query_ticket'
+    url: '{otn}/leftTicket/query?date={date:+0d}'
+`
+
+func TestApiNestedUrl(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.yml", apiNestedUrlYml)
+	api := f.LoadApi()
+	assert.Equal(t, "https://kyfw.12306.cn/otn", api.Urls["otn"])
+	assert.Equal(t, "https://kyfw.12306.cn/otn/leftTicket/query?date={date:+0d}", api.Apis[0].Url)
+
+	f.Api("api.yml", "url:\n  a: '{b}'\n  b: '{a}'\napi:\n  - id: 'x'\n    url: '{a}'\n")
+	assert.Error(t, conf.NewApiConfig().Load())
+}
+
+const apiDefDotenv = `URL.u12306=https://kyfw.12306.cn
+API.0.ID=query_ticket
+API.0.URL={u12306}/otn/leftTicket/query
+API.0.RETRIES=2
+API.0.TIMEOUT=5s
+`
+
+func TestApiDefinitionDotenv(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.env", apiDefDotenv)
+
+	//dotenv中的重试次数是字符串，按字段类型转换为数字
+	api := f.LoadApi()
+	assert.Equal(t, 2, api.Apis[0].Retries)
+	assert.Equal(t, time.Second * 5, api.Apis[0].TimeoutDuration())
+	assert.Equal(t, "https://kyfw.12306.cn/otn/leftTicket/query", api.Apis[0].Url)
+}
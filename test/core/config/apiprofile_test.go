@@ -0,0 +1,98 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+const apiProfileYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+  otn: '{u12306}/otn'
+api:
+  - id: 'station_name'
+    url: '{otn}/resources/js/framework/station_name.js'
+    timeout: '10s'
+    headers:
+      Referer: '{u12306}'
+profiles:
+  dev:
+    url:
+      u12306: 'http://127.0.0.1:9000'
+    api:
+      - id: 'station_name'
+        timeout: '1s'
+        headers:
+          X-Fake: 'true'
+      - id: 'fake_reset'
+        url: '{u12306}/reset'
+`
+
+const apiProfileTestYml = `url:
+  u12306: 'http://127.0.0.1:9100'
+api:
+  - id: 'station_name'
+    retries: 3
+`
+
+func loadApiProfile(f *configFixture, profile string) (*conf.ApiConfig, error) {
+	f.Api("api.yml", apiProfileYml)
+	f.Write("api-test.yml", apiProfileTestYml)
+	api := conf.NewApiConfig().SetProfile(profile)
+	return api, api.Load()
+}
+
+func TestApiProfileSection(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	api, err := loadApiProfile(f, "dev")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "http://127.0.0.1:9000/otn", api.Urls["otn"])
+	assert.Len(t, api.Apis, 2)
+	station := api.Apis[0]
+	assert.Equal(t, "http://127.0.0.1:9000/otn/resources/js/framework/station_name.js", station.Url)
+	assert.Equal(t, "1s", station.Timeout)
+	assert.Equal(t, map[string]string{"Referer": "{u12306}", "X-Fake": "true"}, station.Headers)
+	assert.Equal(t, "http://127.0.0.1:9000/reset", api.Apis[1].Url)
+	assert.Len(t, api.Files(), 1)
+}
+
+func TestApiProfileFile(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	api, err := loadApiProfile(f, "test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "http://127.0.0.1:9100/otn/resources/js/framework/station_name.js", api.Apis[0].Url)
+	assert.Equal(t, 3, api.Apis[0].Retries)
+	assert.Equal(t, "10s", api.Apis[0].Timeout)
+	assert.Len(t, api.Apis, 1)
+	assert.Equal(t, "api-test.yml", filepath.Base(api.Files()[1]))
+}
+
+func TestApiProfileProd(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	api, err := loadApiProfile(f, "prod")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "https://kyfw.12306.cn/otn/resources/js/framework/station_name.js", api.Apis[0].Url)
+	assert.Len(t, api.Apis, 1)
+}
+
+func TestApiProfileInvalid(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.yml", "api:\n  - id: 'a'\n    url: 'http://127.0.0.1'\nprofiles:\n  dev:\n    api:\n      - timeout: '1s'\n")
+
+	err := conf.NewApiConfig().SetProfile("dev").Load()
+	v, ok := err.(*conf.ValidationError)
+	if assert.True(t, ok, "应返回 *ValidationError: %v", err) {
+		assert.Equal(t, "profiles.dev.api[0].id", v.Errors[0].Field)
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const tomlAppConfig = `environment = "dev"
+
+[defaults.logger]
+level = "info"
+
+[[configurations]]
+profile = "dev"
+
+[configurations.server]
+port = "8100"
+
+[configurations.logger]
+maxAge = "24h"
+`
+
+const dotenvAppConfig = `ENVIRONMENT=dev
+DEFAULTS.LOGGER.LEVEL=info
+CONFIGURATIONS.0.PROFILE=prod
+CONFIGURATIONS.1.PROFILE=dev
+CONFIGURATIONS.1.SERVER.PORT=8100
+CONFIGURATIONS.1.LOGGER.MAXAGE=24h
+`
+
+const tomlApiConfig = `[url]
+u12306 = "https://kyfw.12306.cn"
+
+[[api]]
+id = "station_name"
+name = "车站"
+url = "{u12306}/otn/resources/js/framework/station_name.js"
+`
+
+func assertDecodedApplication(t *testing.T, name, content string) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application(name, content)
+
+	app := conf.NewApplicationConfig().SetArgs(nil)
+	if !assert.NoError(t, app.Load()) {
+		return
+	}
+	assert.Equal(t, "dev", app.Environment)
+	assert.Equal(t, "8100", app.Server.Port)
+	assert.Equal(t, "info", app.Logger.Level)
+	assert.Equal(t, "24h", app.Logger.MaxAge)
+}
+
+func TestDecodeTomlApplication(t *testing.T) {
+	assertDecodedApplication(t, "application.toml", tomlAppConfig)
+}
+
+func TestDecodeDotenvApplication(t *testing.T) {
+	assertDecodedApplication(t, "application.env", dotenvAppConfig)
+}
+
+func TestDecodeDotenvVersion(t *testing.T) {
+	//dotenv中的值都是字符串，数字类型的字段需要转换
+	assertDecodedApplication(t, "application.env", "VERSION=1\n" + dotenvAppConfig)
+
+	var content conf.ConfigContent
+	decoder, _ := conf.DecoderFor(".env")
+	if assert.NoError(t, decoder([]byte("VERSION=1\n" + dotenvAppConfig), &content)) {
+		assert.Equal(t, 1, content.Version)
+		assert.Equal(t, "8100", content.Configurations[1].Server.Port)
+	}
+}
+
+func TestDecodeTomlApi(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.toml", tomlApiConfig)
+
+	api := conf.NewApiConfig()
+	if !assert.NoError(t, api.Load()) {
+		return
+	}
+	assert.Equal(t, "https://kyfw.12306.cn/otn/resources/js/framework/station_name.js", api.Apis[0].Url)
+}
+
+func TestDecodeUnknownExtension(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.ini", "environment=dev")
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "application.ini")
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	conf.RegisterDecoder("upper", func(data []byte, v interface{}) error {
+		yml, _ := conf.DecoderFor(".yml")
+		return yml([]byte(strings.ToLower(string(data))), v)
+	})
+	assert.Contains(t, conf.Extensions(), ".upper")
+
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.upper", strings.ToUpper(overrideAppYml))
+
+	app := conf.NewApplicationConfig().SetArgs(nil)
+	if assert.NoError(t, app.Load()) {
+		assert.Equal(t, "8000", app.Server.Port)
+	}
+}
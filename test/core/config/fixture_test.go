@@ -0,0 +1,100 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// configFixture 配置测试使用的临时目录，Close时删除目录并将修改过的环境变量恢复为原来的值
+// 用法:
+//    f := newConfigFixture(t)
+//    defer f.Close()
+//    f.Application("application.yml", content)
+type configFixture struct {
+	t *testing.T
+	Dir string
+	//修改前的环境变量，未设置过的为nil
+	env map[string]*string
+}
+
+func newConfigFixture(t *testing.T) *configFixture {
+	dir, err := ioutil.TempDir("", "config")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	return &configFixture{t: t, Dir: dir, env: make(map[string]*string)}
+}
+
+// Path 临时目录中的文件路径
+func (f *configFixture) Path(name string) string {
+	return filepath.Join(f.Dir, name)
+}
+
+// Write 在临时目录中写入文件，name可以包含子目录，返回文件路径
+func (f *configFixture) Write(name, format string, args ...interface{}) string {
+	file := f.Path(name)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err!=nil {
+		f.t.Fatal(err)
+	}
+	writeFile(f.t, file, format, args...)
+	return file
+}
+
+// Setenv 设置环境变量，Close时恢复原来的值
+func (f *configFixture) Setenv(key, value string) {
+	if _, ok := f.env[key]; !ok {
+		if old, exists := os.LookupEnv(key); exists {
+			f.env[key] = &old
+		}else{
+			f.env[key] = nil
+		}
+	}
+	_ = os.Setenv(key, value)
+}
+
+// Application 写入应用配置文件，并通过环境变量 TRAN_TICKET_APP 指定该文件
+func (f *configFixture) Application(name, format string, args ...interface{}) string {
+	file := f.Write(name, format, args...)
+	f.Setenv(conf.ApplicationEnvVar, file)
+	return file
+}
+
+// Api 写入api配置文件，并通过环境变量 TRAN_TICKET_API 指定该文件
+func (f *configFixture) Api(name, format string, args ...interface{}) string {
+	file := f.Write(name, format, args...)
+	f.Setenv(conf.ApiEnvVar, file)
+	return file
+}
+
+// LoadApplication 使用命令行参数args加载应用配置，加载失败时结束测试
+func (f *configFixture) LoadApplication(args []string) *conf.ApplicationConfig {
+	app := conf.NewApplicationConfig().SetArgs(args)
+	if err := app.Load(); err!=nil {
+		f.t.Fatal(err)
+	}
+	return app
+}
+
+// LoadApi 加载api配置，加载失败时结束测试
+func (f *configFixture) LoadApi() *conf.ApiConfig {
+	api := conf.NewApiConfig()
+	if err := api.Load(); err!=nil {
+		f.t.Fatal(err)
+	}
+	return api
+}
+
+// Close 恢复环境变量并删除临时目录
+func (f *configFixture) Close() {
+	for key, old := range f.env {
+		if old==nil {
+			_ = os.Unsetenv(key)
+		}else{
+			_ = os.Setenv(key, *old)
+		}
+	}
+	_ = os.RemoveAll(f.Dir)
+}
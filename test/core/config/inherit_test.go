@@ -0,0 +1,98 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const inheritAppYml = `environment: dev
+defaults:
+  server:
+    port: '8000'
+  logger:
+    level: info
+    filename: demo
+configurations:
+  - profile: base
+    logger:
+      level: warn
+      maxAge: 24h
+  - profile: dev
+    extends: base
+    logger:
+      level: debug
+`
+
+const inheritAppJson = `{
+  "environment": "dev",
+  "defaults": {"server": {"port": "8000"}, "logger": {"level": "info", "filename": "demo"}},
+  "configurations": [
+    {"profile": "base", "logger": {"level": "warn", "maxAge": "24h"}},
+    {"profile": "dev", "extends": "base", "logger": {"level": "debug"}}
+  ]
+}`
+
+const cycleAppYml = `environment: dev
+configurations:
+  - profile: base
+    extends: dev
+  - profile: dev
+    extends: base
+`
+
+const unknownParentAppYml = `environment: dev
+configurations:
+  - profile: dev
+    extends: missing
+`
+
+func assertInherited(t *testing.T, app *conf.ApplicationConfig) {
+	assert.Equal(t, "dev", app.Profile)
+	assert.Equal(t, "debug", app.Logger.Level)
+	assert.Equal(t, "dev", app.Source("logger.level").Profile)
+	assert.Equal(t, "24h", app.Logger.MaxAge)
+	assert.Equal(t, "base", app.Source("logger.maxAge").Profile)
+	assert.Equal(t, "demo", app.Logger.Filename)
+	assert.Equal(t, conf.DefaultsProfile, app.Source("logger.filename").Profile)
+	assert.Equal(t, conf.LayerDefault, app.Source("logger.rotationTime").Layer)
+}
+
+func TestInheritYml(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", inheritAppYml)
+
+	assertInherited(t, f.LoadApplication(nil))
+}
+
+func TestInheritJson(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.json", inheritAppJson)
+
+	assertInherited(t, f.LoadApplication(nil))
+}
+
+func TestInheritCycle(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", cycleAppYml)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "dev -> base -> dev")
+	}
+}
+
+func TestInheritUnknownParent(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", unknownParentAppYml)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "configurations[dev].extends")
+		assert.Contains(t, err.Error(), "missing")
+	}
+}
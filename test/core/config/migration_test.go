@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+)
+
+//v1中logger.maxAge为小时数，v2改为时长
+const migrationV1Yml = `environment: dev
+configurations:
+  - profile: dev
+    server:
+      port: 8000
+    logger:
+      path: '%s'
+      maxAge: 48
+`
+
+func migrationRegistry() *migrate.Registry {
+	return migrate.NewRegistry("application").Register(1, "logger.maxAge 改为时长", func(doc map[string]interface{}) error {
+		for _, item := range doc["configurations"].([]interface{}) {
+			logger, ok := item.(map[string]interface{})["logger"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if hours, ok := logger["maxAge"].(int); ok {
+				logger["maxAge"] = strconv.Itoa(hours) + "h"
+			}
+		}
+		return nil
+	})
+}
+
+func TestApplicationMigration(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	file := f.Application("application.yml", migrationV1Yml, f.Dir)
+
+	app := conf.NewApplicationConfig().SetArgs(nil).SetMigrations(migrationRegistry()).SetRewriteMigrated(true)
+	if !assert.NoError(t, app.Load()) {
+		return
+	}
+	assert.Equal(t, "48h", app.Logger.MaxAge)
+	assert.Equal(t, "8000", app.Server.Port)
+	if assert.Len(t, app.Migrations(), 1) {
+		assert.Equal(t, 1, app.Migrations()[0].From)
+	}
+
+	backup, err := ioutil.ReadFile(file + ".v1.bak")
+	assert.NoError(t, err)
+	assert.Contains(t, string(backup), "maxAge: 48\n")
+	migrated, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(migrated), "version: 2")
+	assert.Contains(t, string(migrated), "maxAge: 48h")
+
+	//升级后的文件再次加载时不再升级
+	again := conf.NewApplicationConfig().SetArgs(nil).SetMigrations(migrationRegistry())
+	assert.NoError(t, again.Load())
+	assert.Empty(t, again.Migrations())
+}
+
+func TestApplicationMigrationInMemory(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	file := f.Application("application.yml", "# 注释\n" + migrationV1Yml, f.Dir)
+
+	//默认只在内存中升级，config show 等不修改配置文件
+	app := conf.NewApplicationConfig().SetArgs(nil).SetMigrations(migrationRegistry())
+	if !assert.NoError(t, app.Load()) {
+		return
+	}
+	assert.Equal(t, "48h", app.Logger.MaxAge)
+	assert.Len(t, app.Migrations(), 1)
+
+	//重新加载时同样只在内存中升级
+	fresh, err := app.Reload()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "48h", fresh.Logger.MaxAge)
+	}
+
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "# 注释\n" + fmt.Sprintf(migrationV1Yml, f.Dir), string(content))
+	_, err = os.Stat(file + ".v1.bak")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApplicationMigrationNewerVersion(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", "version: 9\n" + migrationV1Yml, f.Dir)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).SetMigrations(migrationRegistry()).Load()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "v9")
+}
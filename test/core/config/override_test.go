@@ -0,0 +1,71 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const overrideAppYml = `environment: dev
+configurations:
+  - profile: dev
+    server:
+      port: '8000'
+    logger:
+      level: debug
+  - profile: prod
+    server:
+      port: '80'
+`
+
+func TestOverrideDefaultAndFile(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", overrideAppYml)
+	app := f.LoadApplication(nil)
+
+	assert.Equal(t, "8000", app.Server.Port)
+	assert.Equal(t, conf.LayerFile, app.Source("server.port").Layer)
+	assert.Equal(t, "1440h", app.Logger.MaxAge)
+	assert.Equal(t, conf.LayerDefault, app.Source("logger.maxAge").Layer)
+}
+
+func TestOverrideEnvVar(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", overrideAppYml)
+	f.Setenv("TRAN_TICKET_SERVER_PORT", "8100")
+	f.Setenv("TRAN_TICKET_LOGGER_MAX_AGE", "24h")
+
+	app := f.LoadApplication(nil)
+
+	assert.Equal(t, "8100", app.Server.Port)
+	assert.Equal(t, conf.Source{Layer: conf.LayerEnv, Location: "TRAN_TICKET_SERVER_PORT"}, app.Source("server.port"))
+	assert.Equal(t, "24h", app.Logger.MaxAge)
+	assert.Equal(t, conf.LayerEnv, app.Source("logger.maxAge").Layer)
+}
+
+func TestOverrideFlag(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", overrideAppYml)
+	f.Setenv("TRAN_TICKET_SERVER_PORT", "8100")
+
+	app := f.LoadApplication([]string{"--server.port=8200", "--logger.level", "warn"})
+
+	assert.Equal(t, "8200", app.Server.Port)
+	assert.Equal(t, conf.Source{Layer: conf.LayerFlag, Location: "--server.port"}, app.Source("server.port"))
+	assert.Equal(t, "warn", app.Logger.Level)
+}
+
+func TestOverrideProfile(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", overrideAppYml)
+	app := f.LoadApplication([]string{"--profile", "prod"})
+
+	assert.Equal(t, "prod", app.Environment)
+	assert.Equal(t, conf.LayerFlag, app.Source("environment").Layer)
+	assert.Equal(t, "80", app.Server.Port)
+	assert.Equal(t, "info", app.Logger.Level)
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+const secretAppYml = `environment: dev
+configurations:
+  - profile: dev
+    database:
+      url: '%s'
+`
+
+// newSecretFixture 创建临时目录并作为数据目录，密钥文件写入临时目录
+func newSecretFixture(t *testing.T) *configFixture {
+	f := newConfigFixture(t)
+	f.Setenv("XDG_DATA_HOME", f.Path("data"))
+	return f
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	f := newSecretFixture(t)
+	defer f.Close()
+
+	encrypted, err := conf.Encrypt("root:123456")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, conf.IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "123456")
+	plain, err := conf.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "root:123456", plain)
+
+	info, err := os.Stat(conf.SecretKeyFile())
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestLoadEncryptedValue(t *testing.T) {
+	f := newSecretFixture(t)
+	defer f.Close()
+
+	encrypted, err := conf.Encrypt("root:123456@tcp(127.0.0.1:3306)/spm")
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.Application("application.yml", secretAppYml, encrypted)
+
+	app := conf.NewApplicationConfig().SetArgs(nil)
+	if assert.NoError(t, app.Load()) {
+		assert.Equal(t, "root:123456@tcp(127.0.0.1:3306)/spm", app.Database.Url)
+		assert.True(t, app.Encrypted("database.url"))
+		for _, v := range conf.NewEffectiveConfig(app, nil).Values {
+			if v.Key=="database.url" {
+				assert.Equal(t, conf.Mask, v.Value)
+			}
+		}
+	}
+}
+
+func TestDecryptFailureNamesField(t *testing.T) {
+	f := newSecretFixture(t)
+	defer f.Close()
+
+	_, err := conf.Encrypt("create key")
+	if !assert.NoError(t, err) {
+		return
+	}
+	file := f.Application("application.yml", secretAppYml, "enc:bm90IGEgdmFsaWQgY2lwaGVydGV4dA==")
+
+	err = conf.NewApplicationConfig().SetArgs(nil).Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	assert.Equal(t, "configurations[dev].database.url", validationErr.Errors[0].Field)
+	assert.Equal(t, file, validationErr.Errors[0].Location)
+}
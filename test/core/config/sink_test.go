@@ -0,0 +1,144 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const sinkYml = `environment: dev
+defaults:
+  logger:
+    sinks:
+      - type: file
+        filename: app.log
+        maxSize: 10MB
+        compress: true
+configurations:
+  - profile: prod
+  - profile: dev
+    extends: prod
+    logger:
+      sinks:
+        - type: console
+          color: true
+        - type: file
+          filename: error.log
+          level: error
+          format: logfmt
+          rotationTime: 24h
+          maxBackups: 5
+`
+
+const invalidSinkYml = `environment: dev
+configurations:
+  - profile: dev
+    logger:
+      sinks:
+        - type: syslog
+        - type: file
+          format: xml
+          level: verbose
+          maxSize: 10XB
+          maxBackups: -1
+`
+
+func TestLogSinks(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", sinkYml)
+
+	app := conf.NewApplicationConfig().SetArgs(nil)
+	if !assert.NoError(t, app.Load()) {
+		return
+	}
+	//子环境的列表整体替换父环境的列表
+	sinks := app.Logger.EffectiveSinks()
+	if assert.Len(t, sinks, 2) {
+		assert.Equal(t, conf.SinkConsole, sinks[0].Type)
+		assert.Equal(t, conf.FormatText, sinks[0].FormatOrDefault())
+		assert.True(t, sinks[0].Color)
+		assert.Equal(t, "error.log", sinks[1].Filename)
+		assert.Equal(t, conf.FormatLogfmt, sinks[1].FormatOrDefault())
+		assert.Equal(t, 5, sinks[1].MaxBackups)
+	}
+	assert.Equal(t, conf.LayerFile, app.Source("logger.sinks").Layer)
+	assert.Equal(t, "dev", app.Source("logger.sinks").Profile)
+
+	f.Setenv(conf.ProfileEnvVar, "prod")
+	app = conf.NewApplicationConfig().SetArgs(nil)
+	if !assert.NoError(t, app.Load()) {
+		return
+	}
+	sinks = app.Logger.EffectiveSinks()
+	if assert.Len(t, sinks, 1) {
+		assert.Equal(t, "app.log", sinks[0].Filename)
+		assert.Equal(t, conf.FormatJson, sinks[0].FormatOrDefault())
+		assert.True(t, sinks[0].Compress)
+	}
+	assert.Equal(t, conf.DefaultsProfile, app.Source("logger.sinks").Profile)
+}
+
+func TestLogSinksDefault(t *testing.T) {
+	app := conf.NewApplicationConfig().SetArgs([]string{"--logger.filename=demo"})
+	if !assert.NoError(t, app.LoadDefaults()) {
+		return
+	}
+	sinks := app.Logger.EffectiveSinks()
+	if assert.Len(t, sinks, 2) {
+		assert.Equal(t, conf.SinkConsole, sinks[0].Type)
+		assert.Equal(t, "demo", sinks[1].Filename)
+		assert.Equal(t, app.Logger.RotationTime, sinks[1].RotationTime)
+	}
+	assert.Equal(t, conf.LayerDefault, app.Source("logger.sinks").Layer)
+}
+
+func TestLogSinksInvalid(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	file := f.Application("application.yml", invalidSinkYml)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	fields := make(map[string]*conf.FieldError)
+	for _, e := range validationErr.Errors {
+		fields[e.Field] = e
+	}
+	assert.Len(t, validationErr.Errors, 6, validationErr.Error())
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[0].type")
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[1].filename")
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[1].format")
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[1].level")
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[1].maxSize")
+	assert.Contains(t, fields, "configurations[dev].logger.sinks[1].maxBackups")
+	assert.Equal(t, file, fields["configurations[dev].logger.sinks[0].type"].Location)
+}
+
+const redactYml = `environment: dev
+defaults:
+  logger:
+    redact:
+      fields: [ 'passengerName' ]
+      patterns: [ 'bankCard=(\d+)', '(' ]
+configurations:
+  - profile: dev
+`
+
+func TestLogRedactInvalid(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	file := f.Application("application.yml", redactYml)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	if assert.Len(t, validationErr.Errors, 1, validationErr.Error()) {
+		assert.Equal(t, "defaults.logger.redact.patterns[1]", validationErr.Errors[0].Field)
+		assert.Equal(t, file, validationErr.Errors[0].Location)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+const invalidAppYml = `environment: dev
+configurations:
+  - profile: dev
+    server:
+      port: '70000'
+    logger:
+      level: verbose
+      maxAge: 60days
+      rotationTime: 24h
+`
+
+const invalidApiYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+api:
+  - id: 'station_name'
+    url: '{u12306}/station_name.js'
+  - id: 'station_name'
+    url: '{u12306}/other.js'
+  - id: 'empty_url'
+`
+
+func TestValidateAggregatesErrors(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	file := f.Application("application.yml", invalidAppYml)
+
+	err := conf.NewApplicationConfig().SetArgs(nil).Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	fields := make(map[string]*conf.FieldError)
+	for _, e := range validationErr.Errors {
+		fields[e.Field] = e
+	}
+	assert.Len(t, validationErr.Errors, 3, validationErr.Error())
+	assert.Contains(t, fields, "configurations[dev].server.port")
+	assert.Contains(t, fields, "configurations[dev].logger.level")
+	assert.Contains(t, fields, "configurations[dev].logger.maxAge")
+	assert.Equal(t, file, fields["configurations[dev].server.port"].Location)
+}
+
+func TestValidateMissingProfile(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Application("application.yml", overrideAppYml)
+
+	err := conf.NewApplicationConfig().SetArgs([]string{"--profile=staging"}).Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	assert.Equal(t, "environment", validationErr.Errors[0].Field)
+	assert.Equal(t, "--profile", validationErr.Errors[0].Location)
+}
+
+func TestValidateApi(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	f.Api("api.yml", invalidApiYml)
+
+	err := conf.NewApiConfig().Load()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	assert.Len(t, validationErr.Errors, 2, validationErr.Error())
+	assert.Equal(t, "api[1].id", validationErr.Errors[0].Field)
+	assert.Equal(t, "api[2].url", validationErr.Errors[1].Field)
+}
+
+func TestValidateAccessLog(t *testing.T) {
+	err := conf.NewApplicationConfig().SetArgs([]string{
+		"--server.accessLog.format= ",
+		"--server.accessLog.skip=/assets/[",
+		"--server.accessLog.slowThreshold=fast",
+		"--server.accessLog.bodySize=4XB",
+	}).LoadDefaults()
+	validationErr, ok := err.(*conf.ValidationError)
+	if !ok {
+		t.Fatalf("预期返回*ValidationError，实际：%v", err)
+	}
+	fields := make(map[string]*conf.FieldError)
+	for _, e := range validationErr.Errors {
+		fields[e.Field] = e
+	}
+	assert.Len(t, validationErr.Errors, 4, validationErr.Error())
+	assert.Contains(t, fields, "server.accessLog.format")
+	assert.Contains(t, fields, "server.accessLog.skip")
+	assert.Contains(t, fields, "server.accessLog.slowThreshold")
+	assert.Equal(t, "--server.accessLog.bodySize", fields["server.accessLog.bodySize"].Location)
+}
+
+func TestValidateLogPathWarning(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	//日志目录是已存在的文件时不可写
+	file := f.Write("log", "")
+
+	app := conf.NewApplicationConfig().SetArgs([]string{"--logger.path=" + file})
+	assert.NoError(t, app.LoadDefaults())
+	if assert.Len(t, app.Warnings(), 1) {
+		assert.Equal(t, "logger.path", app.Warnings()[0].Field)
+		assert.Equal(t, "--logger.path", app.Warnings()[0].Location)
+		assert.Contains(t, app.Warnings()[0].Message, paths.LogDir())
+	}
+}
+
+func TestValidateLogPathNotCreated(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	dir := f.Path("a/b/log")
+
+	app := conf.NewApplicationConfig().SetArgs([]string{"--logger.path=" + dir})
+	assert.NoError(t, app.LoadDefaults())
+	assert.Empty(t, app.Warnings())
+	//校验时不创建日志目录
+	_, err := os.Stat(f.Path("a"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestValidateLogPathUnderFileWarning(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	//上级目录是已存在的文件时无法创建日志目录
+	f.Write("log", "")
+
+	app := conf.NewApplicationConfig().SetArgs([]string{"--logger.path=" + f.Path("log/app")})
+	assert.NoError(t, app.LoadDefaults())
+	if assert.Len(t, app.Warnings(), 1) {
+		assert.Equal(t, "logger.path", app.Warnings()[0].Field)
+		assert.Contains(t, app.Warnings()[0].Message, "不是目录")
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	conf "github.com/abeir/desktop-app/core/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+const watcherAppYml = `environment: dev
+configurations:
+  - profile: dev
+    server:
+      port: '%s'
+    logger:
+      level: %s
+`
+
+const watcherApiYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+api:
+  - id: 'station_name'
+    name: '车站'
+    url: '{u12306}/%s'
+`
+
+func writeFile(t *testing.T, file, format string, args ...interface{}) {
+	content := format
+	if len(args) > 0 {
+		content = fmt.Sprintf(format, args...)
+	}
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err!=nil {
+		t.Fatal(err)
+	}
+}
+
+func loadWatched(f *configFixture) (*conf.ApplicationConfig, *conf.ApiConfig) {
+	f.Application("application.yml", watcherAppYml, "8000", "debug")
+	f.Api("api.yml", watcherApiYml, "station_name.js")
+	return f.LoadApplication(nil), f.LoadApi()
+}
+
+func TestWatcherReloadApplication(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	app, api := loadWatched(f)
+
+	events := make(chan *conf.ApplicationChangeEvent, 1)
+	watcher := conf.NewWatcher(app, api).OnApplicationChange(func(event *conf.ApplicationChangeEvent) {
+		events <- event
+	})
+	assert.NoError(t, watcher.Start())
+	defer watcher.Close()
+
+	writeFile(t, app.File(), watcherAppYml, "9000", "info")
+	select {
+	case event := <-events:
+		assert.Equal(t, "8000", event.Old.Server.Port)
+		assert.Equal(t, "9000", event.New.Server.Port)
+		assert.Equal(t, "info", event.New.Logger.Level)
+		assert.Equal(t, "9000", watcher.Application().Server.Port)
+	case <-time.After(time.Second * 3):
+		t.Fatal("修改application.yml后未收到变化事件")
+	}
+}
+
+func TestWatcherReloadApi(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	app, api := loadWatched(f)
+
+	events := make(chan *conf.ApiChangeEvent, 1)
+	watcher := conf.NewWatcher(app, api).OnApiChange(func(event *conf.ApiChangeEvent) {
+		events <- event
+	})
+	assert.NoError(t, watcher.Start())
+	defer watcher.Close()
+
+	writeFile(t, api.File(), watcherApiYml, "other.js")
+	select {
+	case event := <-events:
+		assert.Equal(t, "https://kyfw.12306.cn/station_name.js", event.Old[0].Url)
+		assert.Equal(t, "https://kyfw.12306.cn/other.js", event.New[0].Url)
+	case <-time.After(time.Second * 3):
+		t.Fatal("修改api.yml后未收到变化事件")
+	}
+}
+
+func TestWatcherKeepLastGoodConfig(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	app, api := loadWatched(f)
+
+	errs := make(chan error, 1)
+	watcher := conf.NewWatcher(app, api).
+		OnApplicationChange(func(event *conf.ApplicationChangeEvent) {
+			t.Error("配置有误时不应通知变化")
+		}).
+		OnError(func(file string, err error) {
+			errs <- err
+		})
+	assert.NoError(t, watcher.Start())
+	defer watcher.Close()
+
+	writeFile(t, app.File(), "environment: dev\nconfigurations: [\n")
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+		assert.Equal(t, "8000", watcher.Application().Server.Port)
+	case <-time.After(time.Second * 3):
+		t.Fatal("application.yml有误时未收到错误通知")
+	}
+}
+
+const watcherProdAppYml = `environment: prod
+configurations:
+  - profile: dev
+  - profile: prod
+`
+
+const watcherProfileApiYml = `url:
+  u12306: 'https://kyfw.12306.cn'
+api:
+  - id: 'station_name'
+    name: '车站'
+    url: '{u12306}/%s'
+profiles:
+  prod:
+    url:
+      u12306: 'https://prod.12306.cn'
+`
+
+func TestWatcherReloadSerialized(t *testing.T) {
+	f := newConfigFixture(t)
+	defer f.Close()
+	app, _ := loadWatched(f)
+	//api配置跟随应用配置的环境
+	api := conf.NewApiConfig().SetProfile(app.Environment)
+	if err := api.Load(); err!=nil {
+		t.Fatal(err)
+	}
+
+	watcher := conf.NewWatcher(app, api)
+	assert.NoError(t, watcher.Start())
+	defer watcher.Close()
+
+	//同时修改两个文件，切换环境后的api配置不能被按原环境重新加载的api配置覆盖
+	writeFile(t, api.File(), watcherProfileApiYml, "other.js")
+	writeFile(t, app.File(), watcherProdAppYml)
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) && watcher.Api().Profile()!="prod" {
+		time.Sleep(time.Millisecond * 50)
+	}
+	//等待可能仍在进行的重新加载
+	time.Sleep(time.Millisecond * 500)
+	assert.Equal(t, "prod", watcher.Application().Environment)
+	assert.Equal(t, "prod", watcher.Api().Profile())
+	assert.Equal(t, "https://prod.12306.cn/other.js", watcher.Api().Apis[0].Url)
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"errors"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	buffer := log.NewRingBuffer(3)
+	logger.AddHook(buffer)
+
+	logger.WithField("module", "net").Debug("连接")
+	logger.WithField("module", "service.station").Info("加载车站")
+	logger.WithField("module", "service").WithError(errors.New("timeout")).Warn("查询失败")
+	logger.Error("保存失败")
+
+	//超出条数时覆盖最早的日志
+	records := buffer.Records(log.Filter{})
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, uint64(2), records[0].Seq)
+		assert.Equal(t, "service.station", records[0].Module)
+		assert.Equal(t, "保存失败", records[2].Message)
+		assert.Equal(t, "timeout", records[1].Fields["error"])
+	}
+	assert.Len(t, buffer.Records(log.Filter{Level: "warn"}), 2)
+	assert.Len(t, buffer.Records(log.Filter{Module: "service"}), 2)
+	assert.Len(t, buffer.Records(log.Filter{Module: "serv"}), 0)
+	assert.Len(t, buffer.Records(log.Filter{Text: "TIMEOUT"}), 1)
+	assert.Len(t, buffer.Records(log.Filter{After: 3}), 1)
+	assert.Len(t, buffer.Records(log.Filter{Until: time.Now().Add(-time.Minute)}), 0)
+	limited := buffer.Records(log.Filter{Limit: 1})
+	if assert.Len(t, limited, 1) {
+		assert.Equal(t, uint64(4), limited[0].Seq)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	sub := log.Subscribe(log.Filter{Module: "subscribe"})
+	log.Module("other").Info("忽略")
+	log.Module("subscribe").Info("推送")
+	select {
+	case record := <-sub.C:
+		assert.Equal(t, "推送", record.Message)
+		assert.Equal(t, "subscribe", record.Module)
+	case <-time.After(time.Second):
+		t.Fatal("未收到订阅的日志")
+	}
+	sub.Close()
+	sub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	records := log.Recent(log.Filter{Module: "subscribe", Limit: 1})
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "推送", records[0].Message)
+	}
+}
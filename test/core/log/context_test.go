@@ -0,0 +1,30 @@
+package log
+
+import (
+	"context"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	entry := log.With(log.Fields{"api": "query_ticket"})
+	hook := test.NewLocal(entry.Logger)
+
+	entry.WithField("train", "G1").Infof("查询余票: %d", 3)
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "查询余票: 3", last.Message)
+		assert.Equal(t, "query_ticket", last.Data["api"])
+		assert.Equal(t, "G1", last.Data["train"])
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Empty(t, log.FromContext(context.Background()).Data)
+
+	entry := log.With(log.Fields{"requestId": "abc"})
+	ctx := log.NewContext(context.Background(), entry)
+	assert.Equal(t, "abc", log.FromContext(ctx).Data["requestId"])
+}
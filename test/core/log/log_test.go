@@ -0,0 +1,66 @@
+package log
+
+import (
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitLogInvalid(t *testing.T) {
+	app := newConfig(logDir)
+	app.Logger.Level = "verbose"
+	assert.Error(t, log.InitLog(app))
+	app = newConfig(logDir)
+	app.Logger.MaxAge = "60天"
+	assert.Error(t, log.InitLog(app))
+	app = newConfig(logDir)
+	app.Logger.Modules = "net"
+	assert.Error(t, log.InitLog(app))
+	//配置有误时不做任何修改
+	assert.Equal(t, "debug", log.GetLevels().Level)
+}
+
+func TestInitLogFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if assert.NoError(t, err) {
+		defer os.RemoveAll(dir)
+	}
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	assert.NoError(t, os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data")))
+	defer func() {
+		assert.NoError(t, log.InitLog(newConfig(logDir)))
+	}()
+
+	//日志目录的上级是文件，无法创建目录
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	assert.NoError(t, log.InitLog(newConfig(filepath.Join(file, "log"), "--logger.filename=fallback")))
+
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	log.Info("写入备用目录")
+	assert.NotNil(t, hook.LastEntry())
+	assert.FileExists(t, filepath.Join(paths.LogDir(), "fallback"))
+	assert.True(t, filepath.HasPrefix(paths.LogDir(), dir))
+}
+
+func TestInitLogKeepHooks(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	entry := log.Module("init")
+
+	//再次初始化时只替换写入文件的hook
+	assert.NoError(t, log.InitLog(newConfig(logDir, "--logger.level=info")))
+	entry.Debug("忽略")
+	assert.Nil(t, hook.LastEntry())
+	entry.Info("重新初始化")
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, "重新初始化", hook.LastEntry().Message)
+	}
+	assert.NoError(t, log.InitLog(newConfig(logDir)))
+}
@@ -0,0 +1,35 @@
+package log
+
+import (
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// logDir TestMain中初始化日志使用的目录
+var logDir string
+
+// newConfig 日志写入dir的应用配置
+func newConfig(dir string, args ...string) *config.ApplicationConfig{
+	app := config.NewApplicationConfig().SetArgs(append([]string{"--logger.path=" + dir, "--logger.level=debug"}, args...))
+	if err := app.LoadDefaults(); err!=nil {
+		panic(err)
+	}
+	return app
+}
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "log")
+	if err!=nil {
+		panic(err)
+	}
+	logDir = dir
+	if err = log.InitLog(newConfig(dir)); err!=nil {
+		panic(err)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"fmt"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestModuleLevel(t *testing.T) {
+	defer func() {
+		_ = log.SetModuleLevels(nil)
+		_ = log.SetLevel("debug")
+	}()
+	assert.NoError(t, log.SetModuleLevels(map[string]string{"service": "error", "service.station": "trace"}))
+
+	assert.Equal(t, "error", log.Module("service").Logger.GetLevel().String())
+	assert.Equal(t, "trace", log.Module("service.station").Logger.GetLevel().String())
+	//未设置的子模块使用上级模块的级别
+	assert.Equal(t, "error", log.Module("service.ticket").Logger.GetLevel().String())
+	assert.Equal(t, "debug", log.Module("net").Logger.GetLevel().String())
+
+	//运行时修改后已创建的模块日志立即生效
+	assert.NoError(t, log.UpdateLevels("info", map[string]string{"service": ""}))
+	assert.Equal(t, "info", log.Module("service").Logger.GetLevel().String())
+	assert.Equal(t, "info", log.Module("service.ticket").Logger.GetLevel().String())
+	assert.Equal(t, "trace", log.Module("service.station").Logger.GetLevel().String())
+	assert.Equal(t, "info", log.Module("net").Logger.GetLevel().String())
+
+	levels := log.GetLevels()
+	assert.Equal(t, "info", levels.Level)
+	assert.Equal(t, map[string]string{"service.station": "trace"}, levels.Modules)
+	assert.Equal(t, "info", levels.Effective["service.ticket"])
+}
+
+func TestUpdateLevelsInvalid(t *testing.T) {
+	defer func() {
+		_ = log.SetModuleLevels(nil)
+		_ = log.SetLevel("debug")
+	}()
+	assert.Error(t, log.UpdateLevels("info", map[string]string{"net": "verbose"}))
+	//任一级别有误时不做任何修改
+	assert.Equal(t, "debug", log.GetLevels().Level)
+	assert.Empty(t, log.GetLevels().Modules)
+	assert.Error(t, log.UpdateLevels("verbose", nil))
+	assert.Error(t, log.SetModuleLevel("", "info"))
+}
+
+func TestModuleHook(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	defer func() {
+		_ = log.SetModuleLevels(nil)
+	}()
+	assert.NoError(t, log.SetModuleLevel("net", "error"))
+
+	log.Module("net").Info("忽略")
+	assert.Nil(t, hook.LastEntry())
+
+	log.Module("net").Error("请求失败")
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "请求失败", last.Message)
+		assert.Equal(t, "net", last.Data["module"])
+	}
+}
+
+func TestModuleConcurrentInit(t *testing.T) {
+	//重新初始化日志的同时创建模块日志，模块日志最终使用新的输出与hook
+	done := make(chan error)
+	go func() {
+		var err error
+		for i:=0; i<3 && err==nil; i++ {
+			err = log.InitLog(newConfig(logDir))
+		}
+		done <- err
+	}()
+	count := 0
+	for running := true; running; count++ {
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+			running = false
+		default:
+			log.Module(fmt.Sprintf("concurrent.%d", count)).Debug("创建模块日志")
+		}
+	}
+
+	global := log.With(nil).Logger
+	for i:=0; i<count; i++ {
+		logger := log.Module(fmt.Sprintf("concurrent.%d", i)).Logger
+		assert.Equal(t, len(global.Hooks[logrus.InfoLevel]), len(logger.Hooks[logrus.InfoLevel]))
+	}
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 日志中不能出现的原始内容
+var sensitiveValues = []string{
+	"110101199003078515",
+	"13812345678",
+	"ABCDEF123456",
+	"tk-secret-value",
+	"Bearer abc.def",
+	"p@ssw0rd",
+	"张三",
+	"6222020000000000",
+}
+
+func TestRedact(t *testing.T) {
+	redactor := log.NewRedactor()
+	assert.NoError(t, redactor.Set([]string{"passengerName"}, []string{`bankCard=(\d+)`}))
+
+	assert.Equal(t, "身份证 " + log.Mask + "，手机 " + log.Mask, redactor.Redact("身份证 110101199003078515，手机 13812345678"))
+	assert.Equal(t, "Cookie: " + log.Mask, redactor.Redact("Cookie: JSESSIONID=ABCDEF123456; tk=tk-secret-value"))
+	assert.Equal(t, "url?tk=" + log.Mask + "&a=1", redactor.Redact("url?tk=tk-secret-value&a=1"))
+	assert.Equal(t, "bankCard=" + log.Mask, redactor.Redact("bankCard=6222020000000000"))
+	//json中的敏感字段，包括设置的字段名
+	assert.Equal(t, `{"username":"u","password":"` + log.Mask + `","passengerName" : "` + log.Mask + `"}`,
+		redactor.Redact(`{"username":"u","password":"p@ss\"w0rd","passengerName" : "张三"}`))
+	//订单号等较长的数字不是手机号
+	assert.Equal(t, "E138123456789", redactor.Redact("E138123456789"))
+	assert.Error(t, redactor.Set(nil, []string{"("}))
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(redactor)
+	hook := test.NewLocal(logger)
+	fields := logrus.Fields{"passengerName": "张三", "password": "p@ssw0rd"}
+	entry := logger.WithFields(fields)
+	entry.WithField("headers", http.Header{"Authorization": {"Bearer abc.def"}, "Accept": {"*/*"}}).Info("登录")
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, log.Mask, last.Data["passengerName"])
+		assert.Equal(t, log.Mask, last.Data["password"])
+		headers := last.Data["headers"].(map[string]interface{})
+		assert.Equal(t, log.Mask, headers["Authorization"])
+		assert.Equal(t, []string{"*/*"}, headers["Accept"])
+	}
+	//不修改调用方的字段
+	assert.Equal(t, "张三", entry.Data["passengerName"])
+}
+
+func TestRedactSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		assert.NoError(t, log.InitLog(newConfig(logDir)))
+	}()
+
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	app := newConfig(dir)
+	app.Logger.Sinks = []config.LogSink{
+		{Type: config.SinkFile, Filename: "app.json"},
+		{Type: config.SinkFile, Filename: "app.log", Format: config.FormatLogfmt},
+	}
+	app.Logger.Redact = config.Redact{Fields: []string{"passengerName"}, Patterns: []string{`bankCard=(\d+)`}}
+	assert.NoError(t, log.InitLog(app))
+
+	log.Module("redact").WithFields(log.Fields{
+		"passengerName": "张三",
+		"cookie": "JSESSIONID=ABCDEF123456; tk=tk-secret-value",
+	}).Infof("乘客证件 %s 手机 %s", "110101199003078515", "13812345678")
+	log.Module("redact").WithError(errors.New("Authorization: Bearer abc.def")).Error("password=p@ssw0rd bankCard=6222020000000000")
+	//关闭文件输出
+	assert.NoError(t, log.InitLog(newConfig(logDir)))
+
+	var outputs []string
+	for _, name := range []string{"app.json", "app.log"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		outputs = append(outputs, string(data))
+	}
+	for _, record := range log.Recent(log.Filter{Module: "redact"}) {
+		outputs = append(outputs, fmt.Sprint(record))
+	}
+	for _, entry := range hook.AllEntries() {
+		outputs = append(outputs, entry.Message + fmt.Sprint(entry.Data))
+	}
+	assert.Len(t, outputs, 6)
+	for _, output := range outputs {
+		assert.Contains(t, output, log.Mask)
+		for _, value := range sensitiveValues {
+			assert.NotContains(t, output, value)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"compress/gzip"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backupFiles 目录中切割后的文件，按文件名排序
+func backupFiles(t *testing.T, dir, name string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err!=nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), name + ".") {
+			files = append(files, info.Name())
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.log")
+	writer := log.NewRotateWriter(file, log.RotateOptions{MaxSize: 10, MaxBackups: 2})
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = writer.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(data))
+	//超出个数的文件被删除
+	backups := backupFiles(t, dir, "app.log")
+	if assert.Len(t, backups, 2) {
+		data, err = ioutil.ReadFile(filepath.Join(dir, backups[1]))
+		assert.NoError(t, err)
+		assert.Equal(t, "third\n", string(data))
+	}
+}
+
+func TestRotateCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.log")
+	//以相同前缀命名的其他日志文件不会被清理
+	assert.NoError(t, ioutil.WriteFile(file + ".error", []byte("error"), 0644))
+	writer := log.NewRotateWriter(file, log.RotateOptions{Compress: true, MaxBackups: 1})
+
+	_, err = writer.Write([]byte("compressed\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Rotate())
+	_, err = writer.Write([]byte("current\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	backups := backupFiles(t, dir, "app.log")
+	if assert.Len(t, backups, 2) {
+		assert.Equal(t, "app.log.error", backups[1])
+		assert.True(t, strings.HasSuffix(backups[0], ".gz"), backups[0])
+		f, err := os.Open(filepath.Join(dir, backups[0]))
+		if assert.NoError(t, err) {
+			defer f.Close()
+			gz, err := gzip.NewReader(f)
+			if assert.NoError(t, err) {
+				data, err := ioutil.ReadAll(gz)
+				assert.NoError(t, err)
+				assert.Equal(t, "compressed\n", string(data))
+			}
+		}
+	}
+}
+
+func TestRotateByTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.log")
+	//上一个切割周期中修改过的文件在打开时先切割
+	assert.NoError(t, ioutil.WriteFile(file, []byte("yesterday\n"), 0644))
+	yesterday := time.Now().Add(-24 * time.Hour)
+	assert.NoError(t, os.Chtimes(file, yesterday, yesterday))
+	writer := log.NewRotateWriter(file, log.RotateOptions{RotationTime: 24 * time.Hour})
+
+	_, err = writer.Write([]byte("today\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "today\n", string(data))
+	assert.Len(t, backupFiles(t, dir, "app.log"), 1)
+}
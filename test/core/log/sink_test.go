@@ -0,0 +1,53 @@
+package log
+
+import (
+	"encoding/json"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() {
+		assert.NoError(t, log.InitLog(newConfig(logDir)))
+	}()
+
+	app := newConfig(dir)
+	app.Logger.Sinks = []config.LogSink{
+		{Type: config.SinkFile, Filename: "app.log", Format: config.FormatLogfmt},
+		{Type: config.SinkFile, Filename: "error.log", Level: "error"},
+	}
+	assert.NoError(t, log.InitLog(app))
+	log.Module("sink").Info("普通日志")
+	log.Module("sink").Error("错误日志")
+	//重新初始化时关闭原有的输出，文件内容已写入
+	assert.NoError(t, log.InitLog(newConfig(logDir)))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], "level=info")
+		assert.Contains(t, lines[0], "module=sink")
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "error.log"))
+	assert.NoError(t, err)
+	lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 1) {
+		entry := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "错误日志", entry["msg"])
+		assert.Equal(t, "error", entry["level"])
+	}
+}
@@ -0,0 +1,82 @@
+package migrate
+
+import (
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newRegistry() *migrate.Registry {
+	return migrate.NewRegistry("test").
+		Register(1, "rename name to title", func(doc map[string]interface{}) error {
+			doc["title"] = doc["name"]
+			delete(doc, "name")
+			return nil
+		}).
+		Register(2, "add count", func(doc map[string]interface{}) error {
+			doc["count"] = 0
+			return nil
+		})
+}
+
+func TestMigrate(t *testing.T) {
+	registry := newRegistry()
+	assert.Equal(t, 3, registry.Current())
+
+	doc := map[string]interface{}{"name": "a"}
+	applied, err := registry.Migrate(doc)
+	assert.NoError(t, err)
+	if assert.Len(t, applied, 2) {
+		assert.Equal(t, "v1 -> v2: rename name to title", applied[0].String())
+	}
+	assert.Equal(t, map[string]interface{}{"title": "a", "count": 0, "version": 3}, doc)
+
+	applied, err = registry.Migrate(map[string]interface{}{"version": 2.0})
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+
+	applied, err = registry.Migrate(doc)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	_, err = registry.Migrate(map[string]interface{}{"version": 4})
+	assert.Error(t, err)
+	_, err = registry.Migrate(map[string]interface{}{"version": "x"})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		registry.Register(1, "again", func(doc map[string]interface{}) error { return nil })
+	})
+}
+
+func TestBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "application.yml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("environment: dev\n"), 0600))
+
+	backup, err := migrate.Backup(file, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, file + ".v1.bak", backup)
+	data, _ := ioutil.ReadFile(backup)
+	assert.Equal(t, "environment: dev\n", string(data))
+
+	second, err := migrate.Backup(file, 1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, backup, second, "已存在的备份文件不应被覆盖")
+}
+
+func TestNormalize(t *testing.T) {
+	value := migrate.Normalize(map[interface{}]interface{}{
+		"a": []interface{}{map[interface{}]interface{}{1: "x"}},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{"1": "x"}},
+	}, value)
+}
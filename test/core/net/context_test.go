@@ -0,0 +1,82 @@
+package net
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/abeir/desktop-app/core/net"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestContextCancel(t *testing.T) {
+	canceled := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			canceled <- true
+		case <-time.After(5 * time.Second):
+			canceled <- false
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50 * time.Millisecond, cancel)
+	start := time.Now()
+	_, err := net.NewHttpClient().RequestContext(ctx, server.URL)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.True(t, <-canceled, "服务端的请求未中断")
+}
+
+func TestRequestTimeout(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	//整个请求的超时时间包括重试前的等待
+	policy := net.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Second
+	policy.Jitter = 0
+	start := time.Now()
+	_, err := net.NewHttpClient().SetRetryPolicy(policy).SetRequestTimeout(100 * time.Millisecond).Request(server.URL)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, 1, count)
+
+	//ctx已取消时不再重试
+	count = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = net.NewHttpClient().SetRetryPolicy(policy).RequestContext(ctx, server.URL)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Equal(t, 0, count)
+}
+
+func TestRequestStreamContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "stream")
+		_, _ = w.Write([]byte("stream content"))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	client := net.NewHttpClient()
+	n, err := client.RequestStreamContext(context.Background(), server.URL, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("stream content")), n)
+	assert.Equal(t, "stream content", out.String())
+	assert.Equal(t, []string{"stream"}, client.ResponseHeaders()["X-Test"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = net.NewHttpClient().RequestStreamContext(ctx, server.URL, &out)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+}
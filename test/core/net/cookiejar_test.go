@@ -0,0 +1,176 @@
+package net
+
+import (
+	"bytes"
+	"github.com/abeir/desktop-app/core/net"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cookieNames(cookies []*http.Cookie) []string{
+	names := make([]string, 0, len(cookies))
+	for _, c := range cookies {
+		names = append(names, c.Name + "=" + c.Value)
+	}
+	return names
+}
+
+func TestCookieJarRules(t *testing.T) {
+	jar := net.NewCookieJar("")
+	u, _ := url.Parse("https://kyfw.12306.cn/otn/login/init")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".12306.cn", Path: "/"},
+		{Name: "otn", Value: "3", Path: "/otn"},
+		{Name: "secure", Value: "4", Path: "/", Secure: true},
+		{Name: "expired", Value: "5", Expires: time.Now().Add(-time.Hour)},
+		{Name: "other", Value: "6", Domain: "example.com"},
+		{Name: "tld", Value: "7", Domain: "cn"},
+		{Name: "suffix", Value: "9", Domain: ".com.cn"},
+	})
+	//不允许设置为公共后缀
+	other, _ := url.Parse("https://www.example.com.cn/")
+	jar.SetCookies(other, []*http.Cookie{{Name: "suffix", Value: "9", Domain: "com.cn"}})
+
+	get := func(raw string) []string {
+		u, _ := url.Parse(raw)
+		return cookieNames(jar.Cookies(u))
+	}
+	//未设置path时使用请求路径所在的目录，path较长的在前
+	assert.Equal(t, []string{"host=1", "otn=3", "domain=2", "secure=4"}, get("https://kyfw.12306.cn/otn/login/check"))
+	assert.Equal(t, []string{"otn=3", "domain=2", "secure=4"}, get("https://kyfw.12306.cn/otn"))
+	assert.Equal(t, []string{"domain=2"}, get("http://kyfw.12306.cn/otnx"))
+	//子域名只发送设置了domain的cookie
+	assert.Equal(t, []string{"domain=2"}, get("http://www.12306.cn/otn/login"))
+	assert.Empty(t, get("https://example.com/"))
+	assert.Empty(t, get("https://other.com.cn/"))
+
+	//Max-Age小于0时删除
+	jar.SetCookies(u, []*http.Cookie{{Name: "domain", Domain: "12306.cn", Path: "/", MaxAge: -1}})
+	assert.Empty(t, get("http://www.12306.cn/"))
+	//同名cookie替换值
+	jar.SetCookies(u, []*http.Cookie{{Name: "otn", Value: "8", Path: "/otn", MaxAge: 60}})
+	assert.Equal(t, []string{"otn=8"}, get("http://kyfw.12306.cn/otn"))
+}
+
+func TestCookieJarPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "cookies", "user.json")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path=="/login" {
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "abc", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "tk", Value: "xyz", Path: "/", MaxAge: 3600})
+			return
+		}
+		c, err := r.Cookie("JSESSIONID")
+		if err!=nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(c.Value))
+	}))
+	defer server.Close()
+
+	jar := net.NewCookieJar(file)
+	assert.NoError(t, jar.Load())
+	_, err = net.NewHttpClient().SetCookieJar(jar).Request(server.URL + "/login")
+	assert.NoError(t, err)
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	//重新读取后，会话cookie仍然发送
+	reloaded := net.NewCookieJar(file)
+	assert.NoError(t, reloaded.Load())
+	client := net.NewHttpClient().SetCookieJar(reloaded)
+	body, err := client.Request(server.URL + "/user")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, client.StatusCode())
+	assert.Equal(t, "abc", string(body))
+	assert.Len(t, reloaded.All(), 2)
+
+	assert.NoError(t, reloaded.Clear())
+	cleared := net.NewCookieJar(file)
+	assert.NoError(t, cleared.Load())
+	assert.Empty(t, cleared.All())
+}
+
+func TestCookieJarLoadInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "user.json")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("{"), 0600))
+	assert.Error(t, net.NewCookieJar(file).Load())
+	//文件不存在时为空
+	assert.NoError(t, net.NewCookieJar(filepath.Join(dir, "none.json")).Load())
+}
+
+func TestCookieJarNetscape(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	text := "# Netscape HTTP Cookie File\n" +
+		"# comment\n\n" +
+		".12306.cn\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tRAIL_DEVICEID\tdevice\n" +
+		"#HttpOnly_kyfw.12306.cn\tFALSE\t/otn\tTRUE\t0\tJSESSIONID\tsession\n" +
+		"kyfw.12306.cn\tFALSE\t/\tFALSE\t1\told\texpired\n"
+	jar := net.NewCookieJar("")
+	n, err := jar.ImportNetscape(strings.NewReader(text))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	u, _ := url.Parse("https://kyfw.12306.cn/otn/index")
+	assert.Equal(t, []string{"JSESSIONID=session", "RAIL_DEVICEID=device"}, cookieNames(jar.Cookies(u)))
+	u, _ = url.Parse("https://www.12306.cn/otn/index")
+	assert.Equal(t, []string{"RAIL_DEVICEID=device"}, cookieNames(jar.Cookies(u)))
+
+	var out bytes.Buffer
+	assert.NoError(t, jar.ExportNetscape(&out))
+	exported := out.String()
+	assert.True(t, strings.HasPrefix(exported, "# Netscape HTTP Cookie File\n"))
+	assert.Contains(t, exported, ".12306.cn\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tRAIL_DEVICEID\tdevice\n")
+	assert.Contains(t, exported, "#HttpOnly_kyfw.12306.cn\tFALSE\t/otn\tTRUE\t0\tJSESSIONID\tsession\n")
+	assert.NotContains(t, exported, "expired")
+
+	//导出的内容可以再次导入
+	copied := net.NewCookieJar("")
+	n, err = copied.ImportNetscape(strings.NewReader(exported))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, jar.All(), copied.All())
+
+	_, err = net.NewCookieJar("").ImportNetscape(strings.NewReader("kyfw.12306.cn\tFALSE\t/\n"))
+	assert.EqualError(t, err, "第1行格式错误，应为以tab分隔的7列")
+}
+
+func TestAccountJar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	old := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", old)
+	_ = os.Setenv("XDG_DATA_HOME", dir)
+
+	assert.Equal(t, filepath.Join(dir, "desktop-app", "cookies", "a%2Fb.json"), net.CookieFile("a/b"))
+	assert.Equal(t, filepath.Join(dir, "desktop-app", "cookies", "a_b.json"), net.CookieFile("a_b"))
+	assert.Equal(t, filepath.Join(dir, "desktop-app", "cookies", "a%252Fb.json"), net.CookieFile("a%2Fb"))
+	assert.Equal(t, filepath.Join(dir, "desktop-app", "cookies", net.DefaultAccount + ".json"), net.CookieFile(""))
+
+	jar, err := net.AccountJar("account-test")
+	assert.NoError(t, err)
+	same, err := net.AccountJar("account-test")
+	assert.NoError(t, err)
+	assert.True(t, jar==same)
+	assert.Equal(t, net.CookieFile("account-test"), jar.File())
+}
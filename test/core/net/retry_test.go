@@ -0,0 +1,157 @@
+package net
+
+import (
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/core/net"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() *net.RetryPolicy{
+	policy := net.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryStatusAndBusyPage(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		switch count {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			_, _ = w.Write([]byte("<html><body>网络繁忙，请稍后重试</body></html>"))
+		default:
+			_, _ = w.Write([]byte("done"))
+		}
+	}))
+	defer server.Close()
+
+	client := net.NewHttpClient().SetRetryPolicy(fastRetryPolicy())
+	body, err := client.Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "done", string(body))
+	assert.Equal(t, http.StatusOK, client.StatusCode())
+	assert.NotEmpty(t, log.Recent(log.Filter{Module: "net", Text: "请求失败，重试: 网络繁忙页面"}))
+
+	//超过最多次数时返回最后一次的响应
+	count = 0
+	policy := fastRetryPolicy()
+	policy.MaxAttempts = 2
+	body, err = net.NewHttpClient().SetRetryPolicy(policy).Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Contains(t, string(body), net.BusyText)
+
+	//未设置重试策略时只请求一次
+	count = 0
+	client = net.NewHttpClient()
+	_, err = client.Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, http.StatusServiceUnavailable, client.StatusCode())
+}
+
+func TestRetryBusyPageJson(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"messages":["网络繁忙"]}`))
+	}))
+	defer server.Close()
+
+	//json等接口数据中包含网络繁忙时不重试
+	_, err := net.NewHttpClient().SetRetryPolicy(fastRetryPolicy()).Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	header := http.Header{}
+	assert.True(t, net.IsBusyPage(http.StatusOK, header, []byte("<html><body>网络繁忙</body></html>")))
+	header.Set("Content-Type", "text/plain")
+	assert.False(t, net.IsBusyPage(http.StatusOK, header, []byte("网络繁忙")))
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	//POST默认不重试
+	client := net.NewHttpClient().SetMethod(net.HttpPost).SetRetryPolicy(fastRetryPolicy())
+	_, err := client.SetBodyMap(map[string][]string{"a": {"1"}}).Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a=1"}, bodies)
+	assert.Equal(t, http.StatusBadGateway, client.StatusCode())
+
+	//允许重试时重新发送请求体
+	bodies = nil
+	policy := fastRetryPolicy()
+	policy.RetryNonIdempotent = true
+	client = net.NewHttpClient().SetMethod(net.HttpPost).SetRetryPolicy(policy)
+	body, err := client.SetBodyStream(strings.NewReader("stream body")).Request(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stream body", "stream body"}, bodies)
+	assert.Equal(t, "stream body", string(body))
+}
+
+func TestRetryTransientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	count := 0
+	policy := fastRetryPolicy()
+	policy.RetryError = func(err error) bool {
+		count++
+		return net.IsTransientError(err)
+	}
+	_, err := net.NewHttpClient().SetRetryPolicy(policy).Request(url)
+	assert.Error(t, err)
+	assert.True(t, net.IsTransientError(err))
+	assert.Equal(t, 3, count)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer slow.Close()
+	_, err = net.NewHttpClient().SetTimeout(10 * time.Millisecond).Request(slow.URL)
+	assert.True(t, net.IsTransientError(err))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &net.RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	assert.Equal(t, time.Duration(0), policy.Backoff(0))
+	assert.Equal(t, 100 * time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 400 * time.Millisecond, policy.Backoff(3))
+	assert.Equal(t, time.Second, policy.Backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		backoff := policy.Backoff(2)
+		assert.True(t, backoff>=100 * time.Millisecond && backoff<=300 * time.Millisecond, backoff.String())
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	assert.True(t, net.IsIdempotent("get"))
+	assert.True(t, net.IsIdempotent("PUT"))
+	assert.False(t, net.IsIdempotent("POST"))
+	assert.False(t, net.IsIdempotent("PATCH"))
+}
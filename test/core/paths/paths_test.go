@@ -0,0 +1,97 @@
+package paths
+
+import (
+	"github.com/abeir/desktop-app/core/paths"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func withXdg(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "paths")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	vars := []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_CONFIG_DIRS", "XDG_DATA_DIRS"}
+	old := make(map[string]string)
+	for _, name := range vars {
+		old[name] = os.Getenv(name)
+	}
+	_ = os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	_ = os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	_ = os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	_ = os.Setenv("XDG_CONFIG_DIRS", filepath.Join(dir, "etc"))
+	_ = os.Setenv("XDG_DATA_DIRS", filepath.Join(dir, "share"))
+	return dir, func() {
+		for name, value := range old {
+			_ = os.Setenv(name, value)
+		}
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestXdgDirs(t *testing.T) {
+	dir, restore := withXdg(t)
+	defer restore()
+
+	assert.Equal(t, filepath.Join(dir, "config", paths.AppName), paths.ConfigDir())
+	assert.Equal(t, filepath.Join(dir, "data", paths.AppName), paths.DataDir())
+	assert.Equal(t, filepath.Join(dir, "cache", paths.AppName), paths.CacheDir())
+	assert.Equal(t, filepath.Join(dir, "data", paths.AppName, "log"), paths.LogDir())
+
+	configDirs := paths.ConfigDirs()
+	assert.Equal(t, paths.ConfigDir(), configDirs[0])
+	assert.Equal(t, filepath.Join(dir, "etc", paths.AppName), configDirs[len(configDirs)-1])
+}
+
+func TestRelativeXdgIgnored(t *testing.T) {
+	_, restore := withXdg(t)
+	defer restore()
+	_ = os.Setenv("XDG_CONFIG_HOME", "relative/config")
+
+	assert.True(t, filepath.IsAbs(paths.ConfigDir()))
+}
+
+func TestEnsureDirs(t *testing.T) {
+	_, restore := withXdg(t)
+	defer restore()
+
+	assert.NoError(t, paths.EnsureDirs())
+	for _, dir := range []string{paths.ConfigDir(), paths.DataDir(), paths.CacheDir(), paths.LogDir()} {
+		info, err := os.Stat(dir)
+		if assert.NoError(t, err) {
+			assert.True(t, info.IsDir())
+		}
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir, restore := withXdg(t)
+	defer restore()
+
+	assert.Equal(t, "", paths.FindConfigFile("application.yml"))
+
+	system := filepath.Join(dir, "etc", paths.AppName)
+	assert.NoError(t, os.MkdirAll(system, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(system, "application.yml"), []byte{}, 0644))
+	assert.Equal(t, filepath.Join(system, "application.yml"), paths.FindConfigFile("application.yml"))
+
+	assert.NoError(t, paths.EnsureDirs())
+	user := filepath.Join(paths.ConfigDir(), "application.json")
+	assert.NoError(t, ioutil.WriteFile(user, []byte{}, 0644))
+	assert.Equal(t, user, paths.FindConfigFile("application.yml", "application.json"))
+}
+
+func TestAssetDir(t *testing.T) {
+	dir, restore := withXdg(t)
+	defer restore()
+
+	shared := filepath.Join(dir, "share", paths.AppName, "ui")
+	assert.NoError(t, os.MkdirAll(filepath.Join(shared, "template"), 0755))
+	assetDir, err := paths.AssetDir()
+	if assert.NoError(t, err) {
+		assert.Equal(t, shared, assetDir)
+	}
+}
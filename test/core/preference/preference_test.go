@@ -0,0 +1,132 @@
+package preference
+
+import (
+	"github.com/abeir/desktop-app/core/migrate"
+	"github.com/abeir/desktop-app/core/preference"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempStore(t *testing.T) (*preference.Store, func()) {
+	dir, err := ioutil.TempDir("", "preference")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	return preference.NewStore(filepath.Join(dir, "preferences.json")), func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestStoreDefaults(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+
+	assert.NoError(t, store.Load())
+	assert.Equal(t, preference.Defaults(), store.Get())
+}
+
+func TestStorePatch(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+
+	var events []*preference.ChangeEvent
+	store.OnChange(func(event *preference.ChangeEvent) {
+		events = append(events, event)
+	})
+	assert.NoError(t, store.Patch([]byte(`{"fromStation":"北京","notification":{"sound":false}}`)))
+	prefs := store.Get()
+	assert.Equal(t, "北京", prefs.FromStation)
+	assert.Equal(t, preference.ThemeSystem, prefs.Theme)
+	assert.True(t, prefs.Notification.Enabled)
+	assert.False(t, prefs.Notification.Sound)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "", events[0].Old.FromStation)
+		assert.Equal(t, "北京", events[0].New.FromStation)
+	}
+
+	//重新加载后保持一致
+	loaded := preference.NewStore(store.File())
+	assert.NoError(t, loaded.Load())
+	assert.Equal(t, prefs, loaded.Get())
+}
+
+func TestStoreReplace(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+
+	assert.NoError(t, store.Patch([]byte(`{"fromStation":"北京","theme":"dark"}`)))
+	assert.NoError(t, store.Replace([]byte(`{"toStation":"上海","seatTypes":["二等座","一等座"]}`)))
+	prefs := store.Get()
+	assert.Equal(t, "", prefs.FromStation)
+	assert.Equal(t, "上海", prefs.ToStation)
+	assert.Equal(t, preference.ThemeSystem, prefs.Theme)
+	assert.Equal(t, []string{"二等座", "一等座"}, prefs.SeatTypes)
+}
+
+func TestStoreInvalid(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+
+	called := false
+	store.OnChange(func(event *preference.ChangeEvent) {
+		called = true
+	})
+	assert.Error(t, store.Patch([]byte(`{"theme":"blue"}`)))
+	assert.Error(t, store.Patch([]byte(`{"unknown":1}`)))
+	assert.Error(t, store.Patch([]byte(`{"theme":`)))
+	assert.Equal(t, preference.Defaults(), store.Get())
+	assert.False(t, called)
+	_, err := os.Stat(store.File())
+	assert.True(t, os.IsNotExist(err), "校验不通过时不应写入文件")
+}
+
+func TestStoreLoadFailedReadOnly(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+	//新版本程序写入的文件，或者被破坏的文件
+	for _, content := range []string{`{"version":99,"fromStation":"北京"}`, `{"fromStation":`} {
+		assert.NoError(t, ioutil.WriteFile(store.File(), []byte(content), 0600))
+		assert.Error(t, store.Load())
+		assert.Error(t, store.LoadError())
+		assert.Equal(t, preference.Defaults(), store.Get())
+
+		//使用默认值时不能覆盖原文件
+		assert.Error(t, store.Patch([]byte(`{"theme":"dark"}`)))
+		assert.Error(t, store.Replace([]byte(`{}`)))
+		data, err := ioutil.ReadFile(store.File())
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	//修复文件后重新加载可以修改
+	assert.NoError(t, ioutil.WriteFile(store.File(), []byte(`{"fromStation":"北京"}`), 0600))
+	assert.NoError(t, store.Load())
+	assert.NoError(t, store.Patch([]byte(`{"theme":"dark"}`)))
+}
+
+func TestStoreMigration(t *testing.T) {
+	store, clean := tempStore(t)
+	defer clean()
+	assert.NoError(t, ioutil.WriteFile(store.File(), []byte(`{"fromStation":"北京","darkMode":true}`), 0600))
+
+	registry := migrate.NewRegistry("preferences").Register(1, "darkMode 改为 theme", func(doc map[string]interface{}) error {
+		if dark, _ := doc["darkMode"].(bool); dark {
+			doc["theme"] = preference.ThemeDark
+		}
+		delete(doc, "darkMode")
+		return nil
+	})
+	assert.NoError(t, store.SetMigrations(registry).Load())
+	assert.Equal(t, preference.ThemeDark, store.Get().Theme)
+	assert.Equal(t, "北京", store.Get().FromStation)
+	assert.Len(t, store.Migrations(), 1)
+
+	data, err := ioutil.ReadFile(store.File())
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"version": 2`)
+	_, err = os.Stat(store.File() + ".v1.bak")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,18 @@
+package core
+
+import (
+	"github.com/abeir/desktop-app/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsSecretName(t *testing.T) {
+	secrets := []string{"password", "Password", "dbPassword", "access_token", "X-Auth-Token", "tk", "uamtk", "JSESSIONID", "Set-Cookie", "apiKey"}
+	for _, name := range secrets {
+		assert.True(t, core.IsSecretName(name), name)
+	}
+	plains := []string{"stk", "username", "url", "monkey", "maxBackups"}
+	for _, name := range plains {
+		assert.False(t, core.IsSecretName(name), name)
+	}
+}
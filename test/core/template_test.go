@@ -0,0 +1,94 @@
+package core
+
+import (
+	"github.com/abeir/desktop-app/core"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTemplateParse(t *testing.T) {
+	args := map[string]string{"url": "http://www.sina.com", "站点": "北京"}
+	result := core.NewTemplate().Parse("{url }/11/{test}/{name:默认}/{now:unix}/{站点}", args)
+	assert.Equal(t, "http://www.sina.com/11/{test}/{name:默认}/{now:unix}/{站点}", result)
+}
+
+func TestTemplateRender(t *testing.T) {
+	args := map[string]string{"from": "北京", "to": "上海"}
+	result, err := core.NewTemplate().Render("从{from}到{to}，经停{via:南京}，{via:{from}}", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "从北京到上海，经停南京，北京", result)
+}
+
+func TestTemplateRenderJson(t *testing.T) {
+	result, err := core.NewTemplate().Strict(true).Render(`{"from":"{from}", "list":[{ "a": 1}]}`, map[string]string{"from": "BJP"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"from":"BJP", "list":[{ "a": 1}]}`, result)
+}
+
+func TestTemplateStrict(t *testing.T) {
+	tmpl := "{a}/{b}/{a}/{c:ok}/{urlencode:{d}}"
+	result, err := core.NewTemplate().Render(tmpl, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "{a}/{b}/{a}/ok/%7Bd%7D", result)
+
+	_, err = core.NewTemplate().Strict(true).Render(tmpl, nil)
+	unresolved, ok := err.(*core.UnresolvedError)
+	if assert.True(t, ok, "应返回 *UnresolvedError: %v", err) {
+		assert.Equal(t, []string{"a", "b", "d"}, unresolved.Names)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl := core.NewTemplate().Strict(true)
+
+	result, err := tmpl.Render("{date:+1d|2006-01-02}", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().AddDate(0, 0, 1).Format("2006-01-02"), result)
+
+	result, err = tmpl.Render("{date}", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().Format("2006-01-02"), result)
+
+	result, err = tmpl.Render("{now:unixms}", nil)
+	assert.NoError(t, err)
+	ms, err := strconv.ParseInt(result, 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Now().UnixNano() / int64(time.Millisecond), ms, 5000)
+
+	result, err = tmpl.Render("station={urlencode:{from}}", map[string]string{"from": "北京 北"})
+	assert.NoError(t, err)
+	assert.Equal(t, "station=" + url.QueryEscape("北京 北"), result)
+
+	//参数优先于同名函数
+	result, err = tmpl.Render("{date:+1d}", map[string]string{"date": "2020-01-01"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-01-01", result)
+
+	_, err = tmpl.Render("{date:tomorrow}", nil)
+	assert.Error(t, err)
+
+	result, err = core.NewTemplate().Func("upper", func(arg string) (string, error) {
+		return arg + "!", nil
+	}).Render("{upper:hi}", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi!", result)
+}
+
+func TestTemplateResolveArgs(t *testing.T) {
+	args := map[string]string{
+		"base": "https://kyfw.12306.cn",
+		"otn": "{base}/otn",
+		"query": "{otn}/leftTicket/{date}",
+	}
+	resolved, err := core.NewTemplate().ResolveArgs(args)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://kyfw.12306.cn/otn", resolved["otn"])
+	assert.Equal(t, "https://kyfw.12306.cn/otn/leftTicket/{date}", resolved["query"])
+
+	_, err = core.NewTemplate().ResolveArgs(map[string]string{"a": "{b}", "b": "{a}"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "参数引用存在循环")
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"github.com/abeir/desktop-app/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	sizes := map[string]int64{
+		"1024": 1024,
+		"512KB": 512 << 10,
+		"10MB": 10 << 20,
+		"10 mb": 10 << 20,
+		"1.5G": 3 << 29,
+		"100B": 100,
+	}
+	for s, expected := range sizes {
+		size, err := core.ParseSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+	for _, s := range []string{"", "MB", "10XB", "-1KB"} {
+		_, err := core.ParseSize(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminConfig(t *testing.T) {
+	api := &config.ApiConfig{
+		Urls: map[string]string{"u12306": "https://kyfw.12306.cn"},
+		Apis: []config.Api{{Id: "station_name", Url: "https://kyfw.12306.cn/station_name.js"}},
+	}
+	adminController := ctlr.NewAdminController(func() (*config.ApplicationConfig, *config.ApiConfig) {
+		return nil, api
+	})
+	w := NewBaseTest("/admin/config", adminController.Config).DoRequest("GET", "/admin/config", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	rs := &model.ResultMessage{Data: &config.EffectiveConfig{}}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code)
+	effective := rs.Data.(*config.EffectiveConfig)
+	assert.Equal(t, "https://kyfw.12306.cn/station_name.js", effective.Apis[0].Url)
+}
+
+func TestAdminConfigNotLoaded(t *testing.T) {
+	adminController := ctlr.NewAdminController(nil)
+	w := NewBaseTest("/admin/config", adminController.Config).DoRequest("GET", "/admin/config", nil)
+
+	rs := &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, model.FailCode, rs.Code)
+}
+
+func TestAdminLogLevels(t *testing.T) {
+	defer func() {
+		_ = log.UpdateLevels("debug", nil)
+		_ = log.SetModuleLevels(nil)
+	}()
+	adminController := ctlr.NewAdminController(nil)
+	body := `{"level":"error","modules":{"net":"trace"}}`
+	w := NewBaseTest("/admin/log/levels", adminController.SetLogLevels).DoRequest("PUT", "/admin/log/levels", strings.NewReader(body))
+
+	rs := &model.ResultMessage{Data: &log.Levels{}}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code)
+	levels := rs.Data.(*log.Levels)
+	assert.Equal(t, "error", levels.Level)
+	assert.Equal(t, "trace", levels.Modules["net"])
+	assert.Equal(t, "trace", log.Module("net").Logger.GetLevel().String())
+
+	w = NewBaseTest("/admin/log/levels", adminController.LogLevels).DoRequest("GET", "/admin/log/levels", nil)
+	rs = &model.ResultMessage{Data: &log.Levels{}}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, "error", rs.Data.(*log.Levels).Level)
+
+	body = `{"modules":{"net":"verbose"}}`
+	w = NewBaseTest("/admin/log/levels", adminController.SetLogLevels).DoRequest("PUT", "/admin/log/levels", strings.NewReader(body))
+	rs = &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, model.FailCode, rs.Code)
+	assert.Equal(t, "trace", log.GetLevels().Modules["net"])
+}
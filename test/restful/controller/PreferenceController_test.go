@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/abeir/desktop-app/core/preference"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreference(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preference")
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := preference.NewStore(filepath.Join(dir, "preferences.json"))
+	preferenceController := ctlr.NewPreferenceController(store)
+
+	w := NewBaseTest("/api/preferences", preferenceController.Patch).
+		DoRequest("PATCH", "/api/preferences", strings.NewReader(`{"theme":"dark"}`))
+	prefs := &preference.Preferences{}
+	rs := &model.ResultMessage{Data: prefs}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code)
+	assert.Equal(t, preference.ThemeDark, prefs.Theme)
+
+	w = NewBaseTest("/api/preferences", preferenceController.Put).
+		DoRequest("PUT", "/api/preferences", strings.NewReader(`{"fromStation":"北京"}`))
+	prefs = &preference.Preferences{}
+	rs = &model.ResultMessage{Data: prefs}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, preference.ThemeSystem, prefs.Theme)
+	assert.Equal(t, "北京", prefs.FromStation)
+
+	w = NewBaseTest("/api/preferences", preferenceController.Get).DoRequest("GET", "/api/preferences", nil)
+	prefs = &preference.Preferences{}
+	rs = &model.ResultMessage{Data: prefs}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, "北京", prefs.FromStation)
+
+	w = NewBaseTest("/api/preferences", preferenceController.Patch).
+		DoRequest("PATCH", "/api/preferences", strings.NewReader(`{"theme":"blue"}`))
+	rs = &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, model.FailCode, rs.Code)
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/abeir/desktop-app/core"
+	"github.com/abeir/desktop-app/core/config"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSetupEngine 创建设置模式的engine，配置文件写入临时目录
+func newSetupEngine(t *testing.T) (*gin.Engine, string){
+	dir, err := ioutil.TempDir("", "setup")
+	assert.NoError(t, err)
+	file := filepath.Join(dir, "application.yml")
+	assert.NoError(t, os.Setenv(config.ApplicationEnvVar, file))
+
+	defaults := config.NewApplicationConfig()
+	defaults.SetArgs(nil)
+	defaults.Server.Port = "8000"
+	onComplete := func() error {
+		app := config.NewApplicationConfig()
+		app.SetArgs(nil)
+		return app.Load()
+	}
+	engine := gin.New()
+	ctlr.SetupRouter(engine, ctlr.NewSetupController(defaults, onComplete))
+	return engine, dir
+}
+
+// localRequest 从本机发送的请求
+func localRequest(method, target string, body io.Reader) *http.Request{
+	req := httptest.NewRequest(method, target, body)
+	req.RemoteAddr = "127.0.0.1:50000"
+	req.Host = "localhost:8000"
+	return req
+}
+
+func postSetup(engine *gin.Engine, form url.Values) *httptest.ResponseRecorder{
+	w := httptest.NewRecorder()
+	req := localRequest("POST", "/setup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetupSave(t *testing.T) {
+	engine, dir := newSetupEngine(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(config.ApplicationEnvVar)
+
+	w := postSetup(engine, url.Values{"profile": {"prod"}, "port": {"8100"}, "level": {"warn"}, "logPath": {dir}})
+	rs := &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code, rs.Msg)
+	assert.Equal(t, "8100", rs.Data.(map[string]interface{})["port"])
+
+	app := config.NewApplicationConfig()
+	app.SetArgs(nil)
+	assert.NoError(t, app.Load())
+	assert.Equal(t, "prod", app.Environment)
+	assert.Equal(t, "8100", app.Server.Port)
+	assert.Equal(t, "warn", app.Logger.Level)
+
+	//配置文件已存在时不能覆盖
+	w = postSetup(engine, url.Values{"profile": {"dev"}})
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, model.FailCode, rs.Code)
+}
+
+func TestSetupSaveInvalid(t *testing.T) {
+	engine, dir := newSetupEngine(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(config.ApplicationEnvVar)
+
+	w := postSetup(engine, url.Values{"profile": {"dev"}, "port": {"70000"}, "level": {"loud"}})
+	rs := &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.FailCode, rs.Code)
+	assert.Len(t, rs.Data, 2)
+	assert.False(t, core.IsExists(filepath.Join(dir, "application.yml")), "配置有误时应删除写入的配置文件")
+}
+
+func TestSetupRedirect(t *testing.T) {
+	engine, dir := newSetupEngine(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(config.ApplicationEnvVar)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, localRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/setup", w.Header().Get("Location"))
+}
+
+func TestSetupLocalOnly(t *testing.T) {
+	engine, dir := newSetupEngine(t)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(config.ApplicationEnvVar)
+
+	//局域网中的其他主机不能写入配置文件
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/setup", strings.NewReader(url.Values{"profile": {"dev"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.168.1.10:50000"
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, core.IsExists(filepath.Join(dir, "application.yml")))
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/abeir/desktop-app/core/config"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/abeir/desktop-app/restful/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func useStationApi(url string) {
+	service.SetApiProvider(func() *config.ApiConfig {
+		return &config.ApiConfig{Apis: []config.Api{{Id: "station_name", Url: url, Response: config.ResponseText}}}
+	})
+}
+
+func TestStationNames(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("var station_names ='@bjb|北京北|VAP|beijingbei'"))
+	}))
+	defer upstream.Close()
+	useStationApi(upstream.URL)
+	defer service.SetApiProvider(nil)
+
+	w := NewBaseTest("/api/stations", ctlr.NewStationController().Names).DoRequest("GET", "/api/stations", nil)
+	rs := &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code)
+	assert.Contains(t, rs.Data, "北京北")
+}
+
+func TestStationNamesCanceled(t *testing.T) {
+	canceled := make(chan bool, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			canceled <- true
+		case <-time.After(5 * time.Second):
+			canceled <- false
+		}
+	}))
+	defer upstream.Close()
+	useStationApi(upstream.URL)
+	defer service.SetApiProvider(nil)
+
+	//页面请求的context取消后，对外的请求随之中断
+	engine := gin.New()
+	engine.GET("/api/stations", ctlr.NewStationController().Names)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/api/stations", nil).WithContext(ctx))
+
+	assert.True(t, <-canceled, "对外的请求未中断")
+	rs := &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), w.Body.String())
+	assert.Equal(t, model.FailCode, rs.Code)
+}
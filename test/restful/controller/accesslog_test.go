@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// accessLogEngine 使用指定请求日志配置的engine
+func accessLogEngine(cfg config.AccessLog) *gin.Engine {
+	engine := gin.New()
+	engine.Use(ctlr.AccessLogger(func() config.AccessLog {
+		return cfg
+	}))
+	engine.GET("/assets/css/app.css", func(ct *gin.Context) {
+		ct.String(http.StatusOK, "body{}")
+	})
+	engine.GET("/slow", func(ct *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		ct.String(http.StatusOK, "slow")
+	})
+	engine.POST("/echo", func(ct *gin.Context) {
+		data, _ := ioutil.ReadAll(ct.Request.Body)
+		ct.String(http.StatusOK, string(data))
+	})
+	return engine
+}
+
+func serve(engine *gin.Engine, method, target, body string) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("User-Agent", "test-agent")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAccessLogSkip(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	engine := accessLogEngine(config.DefaultEnvironmentConfig().Server.AccessLog)
+
+	serve(engine, "GET", "/assets/css/app.css", "")
+	assert.Nil(t, hook.LastEntry())
+	serve(engine, "GET", "/slow", "")
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "request", last.Message)
+		assert.Equal(t, "/slow", last.Data["uri"])
+		assert.Equal(t, logrus.InfoLevel, last.Level)
+	}
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+
+	serve(accessLogEngine(config.AccessLog{Format: config.AccessLogCombined}), "GET", "/slow?a=1", "")
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Regexp(t, `^192\.0\.2\.1 - - \[.+\] "GET /slow\?a=1 HTTP/1\.1" 200 4 "" "test-agent"$`, last.Message)
+		assert.NotEmpty(t, last.Data["requestId"])
+	}
+
+	serve(accessLogEngine(config.AccessLog{Format: "{method} {path} {status} {size} {userAgent}"}), "GET", "/slow?a=1", "")
+	last = hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "GET /slow 200 4 test-agent", last.Message)
+	}
+}
+
+func TestAccessLogSlow(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+
+	serve(accessLogEngine(config.AccessLog{Format: config.AccessLogFields, SlowThreshold: "10ms"}), "GET", "/slow", "")
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, logrus.WarnLevel, last.Level)
+		assert.Equal(t, true, last.Data["slow"])
+	}
+}
+
+func TestAccessLogBody(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	engine := accessLogEngine(config.AccessLog{Format: config.AccessLogFields, BodySize: "5B"})
+
+	//restful模块的级别高于debug时不记录内容
+	assert.NoError(t, log.SetModuleLevel("restful", "info"))
+	serve(engine, "POST", "/echo", "hello world")
+	assert.Len(t, hook.AllEntries(), 1)
+
+	assert.NoError(t, log.SetModuleLevel("restful", "debug"))
+	defer log.SetModuleLevel("restful", "")
+	hook.Reset()
+	serve(engine, "POST", "/echo", "hello world")
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "request", entries[0].Message)
+		assert.Equal(t, logrus.DebugLevel, entries[1].Level)
+		assert.Equal(t, "hello...(truncated)", entries[1].Data["requestBody"])
+		assert.Equal(t, "hello...(truncated)", entries[1].Data["responseBody"])
+	}
+}
+
+func TestAccessLogBodyRedact(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+	engine := accessLogEngine(config.AccessLog{Format: config.AccessLogFields, BodySize: "1KB"})
+	assert.NoError(t, log.SetModuleLevel("restful", "debug"))
+	defer log.SetModuleLevel("restful", "")
+
+	//json请求体中的密码不能出现在日志中
+	serve(engine, "POST", "/echo", `{"username":"u","password":"p@ssw0rd"}`)
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, `{"username":"u","password":"` + log.Mask + `"}`, entries[1].Data["requestBody"])
+		assert.Equal(t, `{"username":"u","password":"` + log.Mask + `"}`, entries[1].Data["responseBody"])
+	}
+}
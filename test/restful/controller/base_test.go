@@ -21,7 +21,7 @@ func NewBaseTest(relativePath string, handler gin.HandlerFunc) *BaseTest {
 }
 
 func (b *BaseTest) DoRequest(method string, target string, body io.Reader) *httptest.ResponseRecorder{
-	b.engine.GET(b.relativePath, b.handler)
+	b.engine.Handle(method, b.relativePath, b.handler)
 
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(method, target, body)
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/core/log"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	hook := test.NewLocal(log.With(nil).Logger)
+	defer hook.Reset()
+
+	var requestId interface{}
+	engine := gin.New()
+	engine.Use(ctlr.Logger())
+	engine.GET("/hello", func(ct *gin.Context) {
+		requestId = ctlr.RequestLog(ct).Data["requestId"]
+		ct.String(http.StatusOK, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/hello?a=1", nil)
+	req.Header.Set(ctlr.RequestIdHeader, "req-1")
+	engine.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-1", requestId)
+	assert.Equal(t, "req-1", w.Header().Get(ctlr.RequestIdHeader))
+	last := hook.LastEntry()
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "req-1", last.Data["requestId"])
+		assert.Equal(t, http.StatusOK, last.Data["status"])
+		assert.Equal(t, "GET", last.Data["method"])
+		assert.Equal(t, "/hello?a=1", last.Data["uri"])
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/hello", nil))
+	assert.NotEmpty(t, w.Header().Get(ctlr.RequestIdHeader))
+	assert.Equal(t, w.Header().Get(ctlr.RequestIdHeader), requestId)
+}
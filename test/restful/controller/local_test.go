@@ -0,0 +1,55 @@
+package controller
+
+import (
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/abeir/desktop-app/core/log"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// routerRequest 使用完整的路由发送请求
+//    remoteAddr: 请求的来源地址
+//    host: Host请求头
+func routerRequest(method, target, remoteAddr, host string, body io.Reader) *httptest.ResponseRecorder {
+	engine := gin.New()
+	ctlr.Router(engine)
+	req := httptest.NewRequest(method, target, body)
+	req.RemoteAddr = remoteAddr
+	req.Host = host
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminConfigLocalOnly(t *testing.T) {
+	assert.Equal(t, http.StatusOK, routerRequest("GET", "/admin/config", "127.0.0.1:50000", "127.0.0.1:8000", nil).Code)
+	assert.Equal(t, http.StatusOK, routerRequest("GET", "/admin/config", "[::1]:50000", "localhost:8000", nil).Code)
+	//局域网中的其他主机
+	assert.Equal(t, http.StatusForbidden, routerRequest("GET", "/admin/config", "192.168.1.10:50000", "192.168.1.2:8000", nil).Code)
+	//DNS重绑定，来源是本机但Host不是
+	assert.Equal(t, http.StatusForbidden, routerRequest("GET", "/admin/config", "127.0.0.1:50000", "evil.example.com:8000", nil).Code)
+}
+
+func TestAdminLogsLocalOnly(t *testing.T) {
+	//日志中包含请求与响应的内容，不能从其他主机读取
+	for _, target := range []string{"/admin/logs", "/admin/logs/view", "/admin/logs/stream"} {
+		w := routerRequest("GET", target, "192.168.1.10:50000", "192.168.1.2:8000", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code, target)
+		assert.NotContains(t, w.Header().Get("Content-Type"), "text/event-stream", target)
+	}
+	assert.Equal(t, http.StatusOK, routerRequest("GET", "/admin/logs", "127.0.0.1:50000", "localhost:8000", nil).Code)
+}
+
+func TestAdminLogLevelsLocalOnly(t *testing.T) {
+	//其他主机不能修改日志级别，如开启debug记录请求内容
+	before := log.GetLevels()
+	w := routerRequest("PUT", "/admin/log/levels", "192.168.1.10:50000", "192.168.1.2:8000",
+		strings.NewReader(`{"level":"debug","modules":{"restful":"debug"}}`))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, before, log.GetLevels())
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/abeir/desktop-app/core/log"
+	ctlr "github.com/abeir/desktop-app/restful/controller"
+	"github.com/abeir/desktop-app/restful/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminLogs(t *testing.T) {
+	log.Module("admin.logs").Warn("管理日志")
+	log.Module("admin.logs").Debug("调试日志")
+	adminController := ctlr.NewAdminController(nil)
+	w := NewBaseTest("/admin/logs", adminController.Logs).DoRequest("GET", "/admin/logs?module=admin&level=info&since=1m", nil)
+
+	records := make([]log.Record, 0)
+	rs := &model.ResultMessage{Data: &records}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs), "解析body json格式错误：" + w.Body.String())
+	assert.Equal(t, model.SuccessCode, rs.Code)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "管理日志", records[0].Message)
+		assert.Equal(t, "warning", records[0].Level)
+	}
+
+	w = NewBaseTest("/admin/logs", adminController.Logs).DoRequest("GET", "/admin/logs?since=yesterday", nil)
+	rs = &model.ResultMessage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), rs))
+	assert.Equal(t, model.FailCode, rs.Code)
+}
+
+// readEvent 读取一个事件，返回id与data
+func readEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	var id, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err!=nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line=="" && data!="" {
+			return id, data
+		}
+		if strings.HasPrefix(line, "id: ") {
+			id = line[len("id: "):]
+		}else if strings.HasPrefix(line, "data: ") {
+			data = line[len("data: "):]
+		}
+	}
+}
+
+func TestAdminStreamLogs(t *testing.T) {
+	log.Module("stream").Info("断线前")
+	missed := log.Recent(log.Filter{Module: "stream", Limit: 1})[0]
+	log.Module("stream").Info("断线期间")
+
+	engine := gin.New()
+	engine.GET("/admin/logs/stream", ctlr.NewAdminController(nil).StreamLogs)
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	req, err := http.NewRequest("GET", server.URL + "/admin/logs/stream?module=stream", nil)
+	if err!=nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(missed.Seq, 10))
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err!=nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	reader := bufio.NewReader(resp.Body)
+
+	//补发断线期间的日志
+	record := log.Record{}
+	_, data := readEvent(t, reader)
+	assert.NoError(t, json.Unmarshal([]byte(data), &record))
+	assert.Equal(t, "断线期间", record.Message)
+
+	log.Module("other").Info("忽略")
+	log.Module("stream").Info("实时日志")
+	id, data := readEvent(t, reader)
+	assert.NoError(t, json.Unmarshal([]byte(data), &record))
+	assert.Equal(t, "实时日志", record.Message)
+	assert.Equal(t, strconv.FormatUint(record.Seq, 10), id)
+}
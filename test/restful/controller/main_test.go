@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestMain 请求日志写入临时目录
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "controller")
+	if err!=nil {
+		panic(err)
+	}
+	app := config.NewApplicationConfig().SetArgs([]string{"--logger.path=" + dir})
+	if err = app.LoadDefaults(); err!=nil {
+		panic(err)
+	}
+	if err = log.InitLog(app); err!=nil {
+		panic(err)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
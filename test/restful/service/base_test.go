@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/abeir/desktop-app/core/config"
+	"github.com/abeir/desktop-app/core/log"
+	"github.com/abeir/desktop-app/restful/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain 重试时会输出日志，日志写入临时目录
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "service")
+	if err!=nil {
+		panic(err)
+	}
+	app := config.NewApplicationConfig()
+	app.SetArgs([]string{"--logger.path=" + dir})
+	if err = app.LoadDefaults(); err!=nil {
+		panic(err)
+	}
+	if err = log.InitLog(app); err!=nil {
+		panic(err)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func useApis(urls map[string]string, apis ...config.Api) {
+	service.SetApiProvider(func() *config.ApiConfig {
+		return &config.ApiConfig{Urls: urls, Apis: apis}
+	})
+}
+
+func TestExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "token-1", r.Header.Get("X-Token"))
+		assert.Equal(t, "1.9137", r.URL.Query().Get("station_version"))
+		assert.Equal(t, "2020-01-02", r.URL.Query().Get("date"))
+		assert.Equal(t, `{"from":"BJP"}`, string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	useApis(map[string]string{"host": server.URL}, config.Api{
+		Id: "query",
+		Url: server.URL + "/query",
+		Get: "station_version=1.9137",
+		Method: "post",
+		Headers: map[string]string{"X-Token": "{token}"},
+		Query: map[string]string{"date": "{date}"},
+		Body: `{"from":"{from}"}`,
+		ContentType: "application/json",
+		Timeout: "5s",
+		Response: config.ResponseJson,
+	})
+	base := &service.BaseService{}
+	rsp, err := base.Execute("query", map[string]string{"token": "token-1", "date": "2020-01-02", "from": "BJP"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	result := struct{ Ok bool `json:"ok"` }{}
+	assert.NoError(t, rsp.Json(&result))
+	assert.True(t, result.Ok)
+	assert.Equal(t, config.ResponseJson, rsp.Type)
+}
+
+func TestExecuteRetries(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	useApis(nil, config.Api{Id: "flaky", Url: server.URL, Retries: 2})
+	rsp, err := (&service.BaseService{}).Execute("flaky", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "done", rsp.Text())
+
+	count = 0
+	useApis(nil, config.Api{Id: "flaky", Url: server.URL, Retries: 1})
+	_, err = (&service.BaseService{}).Execute("flaky", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestExecuteRetriesNonIdempotent(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	//POST不重试，避免重复提交
+	useApis(nil, config.Api{Id: "submit", Url: server.URL, Method: "POST", Retries: 2})
+	_, err := (&service.BaseService{}).Execute("submit", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestExecuteContextCancel(t *testing.T) {
+	count := 0
+	canceled := make(chan bool, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		select {
+		case <-r.Context().Done():
+			canceled <- true
+		case <-time.After(5 * time.Second):
+			canceled <- false
+		}
+	}))
+	defer upstream.Close()
+	useApis(nil, config.Api{Id: "slow", Url: upstream.URL, Retries: 2})
+
+	//页面请求中断后，对外的请求随之中断且不再重试
+	var executeErr error
+	done := make(chan bool)
+	engine := gin.New()
+	engine.GET("/query", func(ct *gin.Context) {
+		_, executeErr = (&service.BaseService{}).ExecuteContext(ct.Request.Context(), "slow", nil)
+		close(done)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	_, err := client.Get(server.URL + "/query")
+	assert.Error(t, err)
+	assert.True(t, <-canceled, "对外的请求未中断")
+	<-done
+	assert.True(t, errors.Is(executeErr, context.Canceled), executeErr)
+	assert.Equal(t, 1, count)
+}
+
+func TestExecuteNotFound(t *testing.T) {
+	useApis(nil)
+	_, err := (&service.BaseService{}).Execute("missing", nil)
+	assert.Error(t, err)
+}